@@ -0,0 +1,810 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+)
+
+// errSilentExit makes a command exit with status 1 when its output already tells why
+var errSilentExit = errors.New("command failed, see the output above")
+
+// Help of the flags shared by the download command and the subcommands
+const (
+	checksumFileUsage = "Take the official checksums from this sha256sum/sha512sum manifest or saved release index instead of go.dev"
+	signingKeyUsage   = "OpenPGP public key file used to verify the .asc signature of each download (no key is embedded, signatures are not checked without it)"
+	cacheDirUsage     = "Directory of the verified archive cache (default: automatedgo in the user cache directory)"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(fs *flag.FlagSet, args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{"scan", "scan [-rev <ref>] [-repo <dir>] [-binaries | -image <tarball>] [-strict] [-json] [dir]", "List the Go version pins or Go binaries found in a directory, git revision or image", runScan},
+	{"diff", "diff [-repo <dir>] [-strict] [-json] [-exit-code] <refA> <refB>", "Show which Go version pins changed between two git revisions", runDiff},
+	{"explain", "explain [-strict] -f <file>", "Explain how the Go version of a file is detected", runExplain},
+	{"verify", "verify [-checksum-file <manifest>] [-json] <archive>...", "Check downloaded archives against the official checksum or a local checksum manifest", runVerify},
+	{"install", "install [-goroot <dir> | -sdk-root <dir>] [-checksum-file <manifest>] [-signing-key <key>] [-no-cache] <version|latest>", "Download, verify and extract a Go release for this machine into a GOROOT directory", runInstall},
+	{"use", "use [-sdk-root <dir>] [-checksum-file <manifest>] [-signing-key <key>] [-no-cache] <version|latest>", "Switch the current toolchain to a version, installing it side by side first when needed", runUse},
+	{"installed", "installed [-sdk-root <dir>] [-json]", "List the toolchains installed side by side and which one is current", runInstalled},
+	{"which", "which [-sdk-root <dir>] [version]", "Print the GOROOT of the current toolchain or of an installed version", runWhich},
+	{"rollback", "rollback [-sdk-root <dir>] [-checksum-file <manifest>] [-signing-key <key>] [-no-cache]", "Switch back to the toolchain that was current before the last switch, downloading it again if it was removed", runRollback},
+	{"history", "history [-sdk-root <dir>] [-json]", "List the toolchains installed and switched to over time", runHistory},
+	{"uninstall", "uninstall [-sdk-root <dir>] [-cache-dir <dir>] [-keep-cache] <version>", "Remove an installed toolchain and its cached archives", runUninstall},
+	{"prune", "prune -keep <n> [-keep-pinned] [-repo <dir> ...] [-sdk-root <dir>] [-cache-dir <dir>] [-dry-run]", "Remove all but the newest installed toolchains and their cached archives, keeping versions pinned in the given repositories", runPrune},
+	{"cache", "cache list | verify | prune -max-size <size> [-dir <dir>] [-json]", "List, verify or prune the cache of downloaded Go archives", runCache},
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Commands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\n    \t%s\n", c.usage, c.summary)
+	}
+}
+
+func newFlagSet(c *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\n%s\n\nFlags:\n", os.Args[0], c.usage, c.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runScan(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	rev := fs.String("rev", "", "Read files from this git revision instead of the working tree")
+	repo := fs.String("repo", ".", "Git repository used with -rev")
+	strict := fs.Bool("strict", true, "Ignore low-confidence version matches")
+	binaries := fs.Bool("binaries", false, "List compiled Go executables and compare their Go version with the latest release")
+	image := fs.String("image", "", "List the Go executables in a `docker save` or OCI layout tarball")
+	latest := fs.String("latest", "", "Latest Go version to compare binaries against (default: fetched from go.dev)")
+	vulnDB := fs.String("vulndb", pkg.VulnDBURL, "Go vulnerability database URL or local mirror directory, 'off' disables the check")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *binaries || *image != "" {
+		if *rev != "" {
+			return errors.New("-binaries and -image cannot be combined with -rev")
+		}
+		var infos []pkg.BinaryInfo
+		var err error
+		if *image != "" {
+			infos, err = pkg.ScanImageTarball(*image)
+		} else {
+			root := "."
+			if fs.NArg() > 0 {
+				root = fs.Arg(0)
+			}
+			infos, err = pkg.FindGoBinaries(root)
+		}
+		if err != nil {
+			return err
+		}
+		return reportBinaries(infos, *latest, *vulnDB, *asJSON, stdout)
+	}
+
+	var src pkg.FileSource
+	if *rev != "" {
+		gitSrc, err := pkg.NewGitRevisionSource(*repo, *rev)
+		if err != nil {
+			return err
+		}
+		src = gitSrc
+	} else {
+		root := "."
+		if fs.NArg() > 0 {
+			root = fs.Arg(0)
+		}
+		src = &pkg.WorkTreeSource{Root: root}
+	}
+
+	pins, err := pkg.ScanVersionPins(src, pkg.ExtractOptions{Strict: *strict})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(stdout, pins)
+	}
+	return pkg.WriteScanReport(stdout, pins)
+}
+
+func reportBinaries(infos []pkg.BinaryInfo, latest, vulnDB string, asJSON bool, stdout io.Writer) error {
+	if latest == "" {
+		var err error
+		if latest, err = pkg.GetLatestVersion(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check the latest Go version: %v\n", err)
+			latest = ""
+		}
+	}
+
+	if vulnDB != "off" && len(infos) > 0 {
+		db, err := pkg.LoadVulnDB(vulnDB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not load the vulnerability database: %v\n", err)
+		} else {
+			db.AnnotateBinaries(infos)
+		}
+	}
+
+	if asJSON {
+		return writeJSON(stdout, struct {
+			LatestVersion string           `json:"latest_version,omitempty"`
+			Binaries      []pkg.BinaryInfo `json:"binaries"`
+		}{latest, infos})
+	}
+	return pkg.WriteBinaryReport(stdout, infos, latest)
+}
+
+func runDiff(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	repo := fs.String("repo", ".", "Git repository to read the revisions from")
+	strict := fs.Bool("strict", true, "Ignore low-confidence version matches")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	exitCode := fs.Bool("exit-code", false, "Exit with status 1 when a version pin changed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("diff requires exactly two revisions")
+	}
+
+	opts := pkg.ExtractOptions{Strict: *strict}
+	var scans [2][]pkg.VersionPin
+	for i, rev := range fs.Args() {
+		src, err := pkg.NewGitRevisionSource(*repo, rev)
+		if err != nil {
+			return err
+		}
+		if scans[i], err = pkg.ScanVersionPins(src, opts); err != nil {
+			return err
+		}
+	}
+
+	changes := pkg.DiffVersionPins(scans[0], scans[1])
+	if *asJSON {
+		if changes == nil {
+			changes = []pkg.PinChange{}
+		}
+		if err := writeJSON(stdout, changes); err != nil {
+			return err
+		}
+	} else {
+		pkg.WriteDiffReport(stdout, changes)
+	}
+	if *exitCode && len(changes) > 0 {
+		return errSilentExit
+	}
+	return nil
+}
+
+func runExplain(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var file string
+	fs.StringVar(&file, "file", "", "File to explain, '-' reads from stdin")
+	fs.StringVar(&file, "f", "", "File to explain (shorthand)")
+	strict := fs.Bool("strict", false, "Explain the strict extraction mode")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if file == "" && fs.NArg() == 1 {
+		file = fs.Arg(0)
+	}
+	if file == "" {
+		fs.Usage()
+		return errors.New("explain requires a file")
+	}
+
+	var content []byte
+	var err error
+	if file == pkg.StdinSource {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := pkg.ExplainVersion(stdout, file, content, pkg.ExtractOptions{Strict: *strict}); err != nil {
+		return errSilentExit
+	}
+	return nil
+}
+
+// openCache returns the archive cache in dir, or in the default location
+func openCache(dir string) (*pkg.ArchiveCache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = pkg.DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	return &pkg.ArchiveCache{Dir: dir}, nil
+}
+
+func runCache(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	dir := fs.String("dir", "", "Cache directory (default: automatedgo in the user cache directory)")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	maxSize := fs.String("max-size", "", "With prune, remove the least recently used archives until the cache fits in this size, such as 500M or 2G")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("cache requires an action: list, verify or prune")
+	}
+	// Flags may come before or after the action
+	action := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments after cache %s: %s", action, strings.Join(fs.Args(), " "))
+	}
+
+	cache, err := openCache(*dir)
+	if err != nil {
+		return err
+	}
+
+	var entries []pkg.CacheEntry
+	switch action {
+	case "list":
+		entries, err = cache.List()
+	case "verify":
+		entries, err = cache.Verify()
+	case "prune":
+		if *maxSize == "" {
+			return errors.New("cache prune requires -max-size")
+		}
+		limit, parseErr := pkg.ParseByteSize(*maxSize)
+		if parseErr != nil {
+			return parseErr
+		}
+		entries, err = cache.Prune(limit)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown cache action %q: must be list, verify or prune", action)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if entries == nil {
+			entries = []pkg.CacheEntry{}
+		}
+		if err := writeJSON(stdout, entries); err != nil {
+			return err
+		}
+	} else {
+		writeCacheReport(stdout, action, cache.Dir, entries)
+	}
+
+	if action == "verify" {
+		for _, e := range entries {
+			if !*e.Valid {
+				return errSilentExit
+			}
+		}
+	}
+	return nil
+}
+
+func writeCacheReport(w io.Writer, action, dir string, entries []pkg.CacheEntry) {
+	if len(entries) == 0 {
+		if action == "prune" {
+			fmt.Fprintln(w, "Nothing to prune")
+		} else {
+			fmt.Fprintf(w, "No cached archives in %s\n", dir)
+		}
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+		switch action {
+		case "verify":
+			status := "ok"
+			if !*e.Valid {
+				status = "corrupt"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Filename, e.SHA256, status)
+		case "prune":
+			fmt.Fprintf(tw, "removed %s\t%s\n", e.Filename, pkg.FormatByteSize(e.Size))
+		default:
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Filename, pkg.FormatByteSize(e.Size), e.LastUsed.Format("2006-01-02"), e.SHA256)
+		}
+	}
+	tw.Flush()
+	if action == "prune" {
+		fmt.Fprintf(w, "Freed %s\n", pkg.FormatByteSize(total))
+	} else {
+		fmt.Fprintf(w, "%d archives, %s in %s\n", len(entries), pkg.FormatByteSize(total), dir)
+	}
+}
+
+// checksumSource returns the manifest given with -checksum-file, or go.dev
+func checksumSource(manifest string) (pkg.ChecksumCalculator, error) {
+	if manifest == "" {
+		return &pkg.DefaultChecksumCalculator{}, nil
+	}
+	return pkg.LoadManifest(manifest)
+}
+
+func runVerify(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	manifest := fs.String("checksum-file", "", checksumFileUsage)
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("verify requires at least one archive")
+	}
+
+	checksum, err := checksumSource(*manifest)
+	if err != nil {
+		return err
+	}
+
+	var results []pkg.ArchiveCheck
+	failed := false
+	for _, file := range fs.Args() {
+		result := pkg.CheckArchive(file, checksum)
+		failed = failed || !result.OK
+		results = append(results, result)
+	}
+
+	if *asJSON {
+		if err := writeJSON(stdout, results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				fmt.Fprintf(stdout, "%s: FAILED (%s)\n", r.File, r.Error)
+			case !r.OK:
+				fmt.Fprintf(stdout, "%s: FAILED (expected %s, got %s)\n", r.File, r.Expected, r.Actual)
+			default:
+				fmt.Fprintf(stdout, "%s: OK\n", r.File)
+			}
+		}
+	}
+	if failed {
+		return errSilentExit
+	}
+	return nil
+}
+
+// downloadFlags are the flags of commands that download and verify archives
+type downloadFlags struct {
+	checksumFile *string
+	signingKey   *string
+	cacheDir     *string
+	noCache      *bool
+	progress     *string
+}
+
+func addDownloadFlags(fs *flag.FlagSet) *downloadFlags {
+	return &downloadFlags{
+		checksumFile: fs.String("checksum-file", "", checksumFileUsage),
+		signingKey:   fs.String("signing-key", "", signingKeyUsage),
+		cacheDir:     fs.String("cache-dir", "", cacheDirUsage),
+		noCache:      fs.Bool("no-cache", false, "Always download the archive instead of reusing a cached copy"),
+		progress:     fs.String("progress", "auto", "Download progress output (auto, bar, plain, json, quiet)"),
+	}
+}
+
+func (f *downloadFlags) service() (*pkg.VersionService, error) {
+	checksum, err := checksumSource(*f.checksumFile)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := pkg.NewProgressReporter(*f.progress, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	service := &pkg.VersionService{
+		Downloader: &pkg.SegmentedDownloader{Connections: 1, MaxRetries: 3, Progress: progress},
+		Remover:    &pkg.DefaultRemover{},
+		Checksum:   checksum,
+		Input:      os.Stdin,
+	}
+	if *f.signingKey != "" {
+		if service.Verifier, err = pkg.LoadSignatureVerifier(*f.signingKey); err != nil {
+			return nil, err
+		}
+	}
+	if !*f.noCache {
+		if service.Cache, err = openCache(*f.cacheDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: archive cache disabled: %v\n", err)
+		}
+	}
+	return service, nil
+}
+
+// resolveVersion turns "latest" into the latest release
+func resolveVersion(version string) (string, error) {
+	if version != "latest" {
+		return strings.TrimPrefix(version, "go"), nil
+	}
+	latest, err := pkg.GetLatestVersion()
+	if err != nil {
+		return "", fmt.Errorf("error checking latest version: %w", err)
+	}
+	return strings.TrimPrefix(latest, "go"), nil
+}
+
+func runInstall(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	goroot := fs.String("goroot", "", "Directory the toolchain is installed in, such as /usr/local/go. An existing installation is replaced (default: go<version> in the SDK root)")
+	sdkRoot := addSDKRootFlag(fs)
+	download := addDownloadFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("install requires a version")
+	}
+
+	version, err := resolveVersion(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	service, err := download.service()
+	if err != nil {
+		return err
+	}
+	if *goroot != "" {
+		return service.Install(version, *goroot, stdout)
+	}
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	return sdk.Install(version, installWith(service, stdout))
+}
+
+// installWith installs toolchains with the download and verification
+// pipeline of service
+func installWith(service *pkg.VersionService, stdout io.Writer) pkg.InstallFunc {
+	return func(version, goroot string) error {
+		return service.Install(version, goroot, stdout)
+	}
+}
+
+func addSDKRootFlag(fs *flag.FlagSet) *string {
+	return fs.String("sdk-root", "", "Directory the toolchains are installed in side by side (default: ~/.automatedgo/sdk)")
+}
+
+func openSDK(root string) (*pkg.SDKManager, error) {
+	if root == "" {
+		var err error
+		if root, err = pkg.DefaultSDKRoot(); err != nil {
+			return nil, err
+		}
+	}
+	return &pkg.SDKManager{Root: root}, nil
+}
+
+func runUse(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	download := addDownloadFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("use requires a version")
+	}
+
+	version, err := resolveVersion(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	if !sdk.IsInstalled(version) {
+		service, err := download.service()
+		if err != nil {
+			return err
+		}
+		if err := sdk.Install(version, installWith(service, stdout)); err != nil {
+			return err
+		}
+	}
+	previous, err := sdk.Current()
+	if err != nil {
+		return err
+	}
+	if err := sdk.Use(version); err != nil {
+		return err
+	}
+	reportSwitch(sdk, previous, version, stdout)
+	return nil
+}
+
+// reportSwitch prints a switch between toolchains, the same way for use and
+// rollback
+func reportSwitch(sdk *pkg.SDKManager, from, to string, stdout io.Writer) {
+	pkg.WriteTransition(stdout, from, to)
+	fmt.Fprintf(stdout, "GOROOT: %s\n", sdk.GOROOT(to))
+	if bin := filepath.Join(sdk.Root, pkg.CurrentLink, "bin"); !inPath(bin) {
+		fmt.Fprintf(stdout, "Add %s to your PATH to use it\n", bin)
+	}
+}
+
+func runRollback(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	download := addDownloadFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("rollback takes no arguments")
+	}
+
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	// A previous version that was pruned since is downloaded again
+	from, to, err := sdk.Rollback(func(version, goroot string) error {
+		service, err := download.service()
+		if err != nil {
+			return err
+		}
+		return installWith(service, stdout)(version, goroot)
+	})
+	if err != nil {
+		return err
+	}
+	reportSwitch(sdk, from, to, stdout)
+	return nil
+}
+
+func runHistory(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	history, err := sdk.History()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if history == nil {
+			history = []pkg.HistoryEntry{}
+		}
+		return writeJSON(stdout, history)
+	}
+	if len(history) == 0 {
+		fmt.Fprintf(stdout, "No toolchain history in %s\n", sdk.Root)
+		return nil
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, e := range history {
+		switch {
+		case e.Action == "install":
+			fmt.Fprintf(tw, "%s\tinstall\t%s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Version)
+		case e.Previous == "":
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Action, e.Version)
+		default:
+			fmt.Fprintf(tw, "%s\t%s\t%s -> %s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Action, e.Previous, e.Version)
+		}
+	}
+	return tw.Flush()
+}
+
+func inPath(dir string) bool {
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(entry) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func runInstalled(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	sdks, err := sdk.Installed()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if sdks == nil {
+			sdks = []pkg.SDK{}
+		}
+		return writeJSON(stdout, sdks)
+	}
+	if len(sdks) == 0 {
+		fmt.Fprintf(stdout, "No Go versions installed in %s\n", sdk.Root)
+		return nil
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, s := range sdks {
+		marker := " "
+		if s.Current {
+			marker = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", marker, s.Version, s.GOROOT)
+	}
+	return tw.Flush()
+}
+
+func runWhich(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return errors.New("which takes at most one version")
+	}
+
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	goroot, err := sdk.Which(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, goroot)
+	return nil
+}
+
+// removeToolchain uninstalls version and, unless cache is nil, removes its
+// cached archives
+func removeToolchain(sdk *pkg.SDKManager, cache *pkg.ArchiveCache, version string, stdout io.Writer) error {
+	if err := sdk.Uninstall(version); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Removed Go %s from %s\n", version, sdk.Root)
+	if cache == nil {
+		return nil
+	}
+	removed, err := cache.RemoveVersion(version)
+	for _, e := range removed {
+		fmt.Fprintf(stdout, "Removed cached %s (%s)\n", e.Filename, pkg.FormatByteSize(e.Size))
+	}
+	return err
+}
+
+func runUninstall(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	cacheDir := fs.String("cache-dir", "", cacheDirUsage)
+	keepCache := fs.Bool("keep-cache", false, "Keep the cached archives of the version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("uninstall requires a version")
+	}
+
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	var cache *pkg.ArchiveCache
+	if !*keepCache {
+		if cache, err = openCache(*cacheDir); err != nil {
+			return err
+		}
+	}
+	return removeToolchain(sdk, cache, strings.TrimPrefix(fs.Arg(0), "go"), stdout)
+}
+
+func runPrune(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	sdkRoot := addSDKRootFlag(fs)
+	cacheDir := fs.String("cache-dir", "", cacheDirUsage)
+	keep := fs.Int("keep", -1, "Number of newest installed versions to keep, the current version is always kept")
+	keepPinned := fs.Bool("keep-pinned", false, "Also keep every version pinned in the repositories given with -repo")
+	var repos stringList
+	fs.Var(&repos, "repo", "Repository scanned for version pins with -keep-pinned (repeatable, default: the current directory)")
+	dryRun := fs.Bool("dry-run", false, "Only print which versions would be removed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keep < 0 {
+		fs.Usage()
+		return errors.New("prune requires -keep")
+	}
+	if len(repos) > 0 && !*keepPinned {
+		return errors.New("-repo is only used with -keep-pinned")
+	}
+
+	var pins []string
+	if *keepPinned {
+		if len(repos) == 0 {
+			repos = stringList{"."}
+		}
+		var err error
+		if pins, err = pkg.PinnedVersions(repos); err != nil {
+			return err
+		}
+	}
+
+	sdk, err := openSDK(*sdkRoot)
+	if err != nil {
+		return err
+	}
+	remove, err := sdk.PruneCandidates(*keep, pins)
+	if err != nil {
+		return err
+	}
+	if len(remove) == 0 {
+		fmt.Fprintln(stdout, "Nothing to prune")
+		return nil
+	}
+	if *dryRun {
+		for _, s := range remove {
+			fmt.Fprintf(stdout, "Would remove Go %s from %s\n", s.Version, s.GOROOT)
+		}
+		return nil
+	}
+
+	cache, err := openCache(*cacheDir)
+	if err != nil {
+		return err
+	}
+	for _, s := range remove {
+		if err := removeToolchain(sdk, cache, s.Version, stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
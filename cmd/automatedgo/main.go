@@ -1,42 +1,177 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Nicconike/AutomatedGo/v2/pkg"
 )
 
+// stringList collects the values of a flag that may be repeated
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func runSubcommand(c *command, args []string) int {
+	err := c.run(newFlagSet(c), args, os.Stdout)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errSilentExit):
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if c := findCommand(os.Args[1]); c != nil {
+			os.Exit(runSubcommand(c, os.Args[2:]))
+		}
+	}
+
 	// Define flags
-	versionFile := flag.String("file", "", "Path to file containing current Go version")
+	var versionFiles stringList
+	flag.Var(&versionFiles, "file", "Path or glob of a file containing current Go version, '-' reads from stdin (repeatable)")
 	currentVersion := flag.String("version", "", "Current Go version")
-	targetOS := flag.String("os", "", "Target operating system (windows, linux, darwin)")
-	targetArch := flag.String("arch", "", "Target architecture (386, amd64, armv6l)")
+	targetOS := flag.String("os", "", "Target operating system (windows, linux, darwin, freebsd, ...), a comma separated list downloads several")
+	targetArch := flag.String("arch", "", "Target architecture (386, amd64, arm64, armv6l, riscv64, ...), a comma separated list downloads several")
+	platforms := flag.String("platforms", "", "Comma separated os/arch pairs to download in one run, such as linux/amd64,darwin/arm64")
+	strict := flag.Bool("strict", false, "Reject low-confidence version matches and fail on ambiguous files")
+	installed := flag.Bool("installed", false, "Use the installed Go toolchain ($GOROOT or go on PATH) as the current version")
+	goroot := flag.String("goroot", "", "Use the Go installation in this directory as the current version")
+	combine := flag.String("combine", "lowest", "How to combine versions from multiple files (lowest, highest, error)")
+	prompt := flag.Bool("prompt", false, "Ask for the target OS and architecture when they are not given instead of using this machine's platform")
+	kind := flag.String("kind", "archive", "Kind of release file to download (archive, installer, source)")
+	connections := flag.Int("connections", 1, "Number of parallel connections used to download the archive")
+	progressMode := flag.String("progress", "auto", "Download progress output (auto, bar, plain, json, quiet), auto draws a bar only on a terminal")
+	cacheDir := flag.String("cache-dir", "", cacheDirUsage)
+	noCache := flag.Bool("no-cache", false, "Always download the archive instead of reusing a cached copy")
+	limitRate := flag.String("limit-rate", "", "Maximum download speed in bytes per second shared by all downloads, such as 500K or 5M")
+	jobs := flag.Int("jobs", pkg.DefaultBatchWorkers, "Maximum number of platforms downloaded at the same time with -platforms")
+	retries := flag.Int("retries", 3, "Number of times an interrupted download is resumed before giving up")
+	checksumFile := flag.String("checksum-file", "", checksumFileUsage)
+	signingKey := flag.String("signing-key", "", signingKeyUsage)
 
 	// Add aliases for short versions
-	flag.StringVar(versionFile, "f", "", "Path to file containing current Go version (shorthand)")
+	flag.Var(&versionFiles, "f", "Path or glob of a file containing current Go version (shorthand)")
 	flag.StringVar(currentVersion, "v", "", "Current Go version (shorthand)")
 
 	// Custom usage message
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s [-os=<OS>] [-arch=<ARCH>] (-file|-f=<path> | -version|-v=<version>)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s [-os=<OS>] [-arch=<ARCH>] [-platforms=<os/arch,...>] [-strict] [-combine=<mode>] (-file|-f=<path> ... | -version|-v=<version> | -installed | -goroot=<dir>)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s <command> [flags]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		printCommands(os.Stderr)
 	}
 
 	flag.Parse()
 
+	combineMode, err := pkg.ParseCombineMode(*combine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	fileKind, err := pkg.ParseFileKind(*kind)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var rateLimit *pkg.RateLimiter
+	if *limitRate != "" {
+		bytesPerSecond, err := pkg.ParseByteSize(*limitRate)
+		if err != nil || bytesPerSecond <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid -limit-rate %q\n", *limitRate)
+			os.Exit(2)
+		}
+		rateLimit = pkg.NewRateLimiter(bytesPerSecond)
+	}
+
+	batch, err := pkg.ResolvePlatforms(*platforms, *targetOS, *targetArch)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// Every platform would download the same file at the same time
+	if len(batch) > 0 && fileKind == pkg.KindSource {
+		fmt.Fprintln(os.Stderr, "-kind source cannot be combined with a list of platforms, the source tarball is the same for every platform")
+		os.Exit(2)
+	}
+	// Concurrent progress bars would overwrite each other
+	if len(batch) > 0 && *progressMode == pkg.ProgressAuto {
+		*progressMode = pkg.ProgressPlain
+	}
+
+	progress, err := pkg.NewProgressReporter(*progressMode, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	checksum, err := checksumSource(*checksumFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var verifier pkg.Verifier
+	if *signingKey != "" {
+		if verifier, err = pkg.LoadSignatureVerifier(*signingKey); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	var cache *pkg.ArchiveCache
+	if !*noCache {
+		if cache, err = openCache(*cacheDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: archive cache disabled: %v\n", err)
+		}
+	}
+
 	// Initialize the VersionService with default implementations
 	service := &pkg.VersionService{
-		Downloader: &pkg.DefaultDownloader{},
+		Downloader: &pkg.SegmentedDownloader{Connections: *connections, MaxRetries: *retries, Progress: progress, RateLimit: rateLimit},
 		Remover:    &pkg.DefaultRemover{},
-		Checksum:   &pkg.DefaultChecksumCalculator{},
+		Checksum:   checksum,
+		Cache:      cache,
+		Verifier:   verifier,
+		Input:      os.Stdin,
+		Strict:     *strict,
+		Kind:       fileKind,
+		Prompt:     *prompt,
 	}
 
-	if err := pkg.Run(service, *versionFile, *currentVersion, *targetOS, *targetArch, os.Stdin, os.Stdout); err != nil {
+	config := pkg.RunConfig{
+		VersionFiles:   versionFiles,
+		CurrentVersion: *currentVersion,
+		Installed:      *installed,
+		GOROOT:         *goroot,
+		Combine:        combineMode,
+		TargetOS:       *targetOS,
+		TargetArch:     *targetArch,
+		Platforms:      batch,
+		Workers:        *jobs,
+		Input:          os.Stdin,
+		Output:         os.Stdout,
+	}
+	if err := pkg.RunWithConfig(service, config); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DefaultBatchWorkers is how many platforms are downloaded at the same time
+const DefaultBatchWorkers = 4
+
+type BatchResult struct {
+	Platform Platform
+	Err      error
+}
+
+// ResolvePlatforms turns the -platforms list ("linux/amd64,darwin/arm64"), or
+// comma separated -os and -arch lists, into the platforms of a batch
+// download. It returns nil when a single platform is requested.
+func ResolvePlatforms(platforms, oses, arches string) ([]Platform, error) {
+	if platforms != "" {
+		if oses != "" || arches != "" {
+			return nil, errors.New("-platforms cannot be combined with -os or -arch")
+		}
+		var result []Platform
+		for _, spec := range splitList(platforms) {
+			targetOS, arch, ok := strings.Cut(spec, "/")
+			if !ok || targetOS == "" || arch == "" {
+				return nil, fmt.Errorf("invalid platform %q: must be os/arch", spec)
+			}
+			result = appendPlatform(result, NormalizePlatform(targetOS, arch))
+		}
+		return result, nil
+	}
+
+	if !strings.Contains(oses, ",") && !strings.Contains(arches, ",") {
+		return nil, nil
+	}
+	if oses == "" || arches == "" {
+		return nil, errors.New("lists of operating systems and architectures need both -os and -arch")
+	}
+	var result []Platform
+	for _, targetOS := range splitList(oses) {
+		for _, arch := range splitList(arches) {
+			result = appendPlatform(result, NormalizePlatform(targetOS, arch))
+		}
+	}
+	return result, nil
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func appendPlatform(platforms []Platform, p Platform) []Platform {
+	for _, existing := range platforms {
+		if existing == p {
+			return platforms
+		}
+	}
+	return append(platforms, p)
+}
+
+// RunBatch downloads every platform with at most workers downloads running at
+// the same time. The output of each download is buffered and written with a
+// platform prefix once it is done, so concurrent downloads do not interleave.
+func RunBatch(platforms []Platform, workers int, output io.Writer, download func(p Platform, output io.Writer) error) []BatchResult {
+	if workers < 1 {
+		workers = DefaultBatchWorkers
+	}
+
+	results := make([]BatchResult, len(platforms))
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(platforms); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var buf bytes.Buffer
+				err := download(platforms[i], &buf)
+				results[i] = BatchResult{Platform: platforms[i], Err: err}
+
+				mu.Lock()
+				writePrefixed(output, "["+platforms[i].String()+"] ", &buf)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range platforms {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func writePrefixed(w io.Writer, prefix string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			fmt.Fprintf(w, "%s%s\n", prefix, line)
+		}
+	}
+}
+
+// WriteBatchSummary prints the outcome of every platform and returns an
+// error when at least one download failed
+func WriteBatchSummary(w io.Writer, results []BatchResult) error {
+	failed := 0
+	fmt.Fprintln(w, "Summary:")
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "  %-16s failed: %s\n", r.Platform, r.Err)
+		} else {
+			fmt.Fprintf(w, "  %-16s ok\n", r.Platform)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d downloads succeeded\n", len(results)-failed, len(results))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d downloads failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type BinaryInfo struct {
+	Path       string            `json:"path"`
+	GoVersion  string            `json:"go_version"`
+	ModulePath string            `json:"module_path,omitempty"`
+	Settings   map[string]string `json:"settings,omitempty"`
+	Layer      string            `json:"layer,omitempty"`
+
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+var executableMagics = [][]byte{
+	[]byte("\x7fELF"),
+	[]byte("MZ"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf},
+	{0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+}
+
+// IsExecutableHeader reports whether data starts like an ELF, PE or Mach-O file
+func IsExecutableHeader(data []byte) bool {
+	for _, magic := range executableMagics {
+		if bytes.HasPrefix(data, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+func ReadBinaryInfo(path string) (*BinaryInfo, error) {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Go build info from %s: %w", path, err)
+	}
+	return newBinaryInfo(path, bi), nil
+}
+
+func ReadBinaryInfoFrom(r io.ReaderAt, name string) (*BinaryInfo, error) {
+	bi, err := buildinfo.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Go build info from %s: %w", name, err)
+	}
+	return newBinaryInfo(name, bi), nil
+}
+
+func newBinaryInfo(path string, bi *buildinfo.BuildInfo) *BinaryInfo {
+	info := &BinaryInfo{
+		Path:       path,
+		GoVersion:  binaryGoVersion(bi.GoVersion),
+		ModulePath: bi.Main.Path,
+		Settings:   make(map[string]string, len(bi.Settings)),
+	}
+	if info.ModulePath == "" {
+		info.ModulePath = bi.Path
+	}
+	for _, s := range bi.Settings {
+		info.Settings[s.Key] = s.Value
+	}
+	return info
+}
+
+// binaryGoVersion turns "go1.22.5 X:boringcrypto" into "1.22.5"
+func binaryGoVersion(version string) string {
+	if fields := strings.Fields(version); len(fields) > 0 {
+		version = fields[0]
+	}
+	return strings.TrimPrefix(version, "go")
+}
+
+// FindGoBinaries walks root and returns every executable that carries Go build info
+func FindGoBinaries(root string) ([]BinaryInfo, error) {
+	var infos []BinaryInfo
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !hasExecutableHeader(path) {
+			return nil
+		}
+		if info, err := ReadBinaryInfo(path); err == nil {
+			infos = append(infos, *info)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos, nil
+}
+
+func hasExecutableHeader(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	return IsExecutableHeader(header[:n])
+}
+
+// WriteBinaryReport prints each binary and its status. latestVersion may be
+// empty when it is unknown, vulnerabilities are only listed when annotated.
+func WriteBinaryReport(w io.Writer, infos []BinaryInfo, latestVersion string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BINARY\tGO VERSION\tMODULE\tSTATUS")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Path, info.GoVersion, info.ModulePath, binaryStatus(info, latestVersion))
+	}
+	return tw.Flush()
+}
+
+func binaryStatus(info BinaryInfo, latestVersion string) string {
+	status := "unknown"
+	if latestVersion != "" {
+		status = "up to date"
+		if IsNewer(latestVersion, info.GoVersion) {
+			status = "outdated"
+		}
+	}
+	if len(info.Vulnerabilities) > 0 {
+		ids := make([]string, 0, len(info.Vulnerabilities))
+		for _, v := range info.Vulnerabilities {
+			ids = append(ids, v.ID)
+		}
+		status += ", vulnerable: " + strings.Join(ids, " ")
+	}
+	return status
+}
@@ -0,0 +1,219 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveCache keeps verified archives under <Dir>/<sha256>/<filename>, so an
+// entry can only be found with the checksum it was verified against
+type ArchiveCache struct {
+	Dir string
+}
+
+type CacheEntry struct {
+	Filename string    `json:"filename"`
+	SHA256   string    `json:"sha256"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+	Valid    *bool     `json:"valid,omitempty"`
+}
+
+// DefaultCacheDir is the automatedgo directory in the user's cache directory
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "automatedgo"), nil
+}
+
+func (c *ArchiveCache) path(filename, sha256 string) string {
+	return filepath.Join(c.Dir, strings.ToLower(sha256), filename)
+}
+
+// Lookup returns the cached archive for filename when its content still
+// matches sha256. An entry that no longer matches is removed.
+func (c *ArchiveCache) Lookup(filename, sha256 string) (string, bool) {
+	path := c.path(filename, sha256)
+	sum, err := hashFile(path)
+	if err != nil {
+		return "", false
+	}
+	if !strings.EqualFold(sum, sha256) {
+		_ = os.RemoveAll(filepath.Dir(path))
+		return "", false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return path, true
+}
+
+// Store adds a verified archive to the cache
+func (c *ArchiveCache) Store(archive, filename, sha256 string) error {
+	dest := c.path(filename, sha256)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+	tmp := dest + ".tmp"
+	if err := linkOrCopy(archive, tmp); err != nil {
+		return fmt.Errorf("error adding %s to the cache: %w", filename, err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error adding %s to the cache: %w", filename, err)
+	}
+	return nil
+}
+
+// CopyTo places the cached archive at dest, through a temporary file so dest
+// never holds a partial copy
+func (c *ArchiveCache) CopyTo(cached, dest string) error {
+	tmp := partialFilename(dest)
+	if err := linkOrCopy(cached, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// linkOrCopy hard links src to dest and copies it when linking is not possible
+func linkOrCopy(src, dest string) error {
+	_ = os.Remove(dest)
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	return out.Close()
+}
+
+// List returns the cached archives, most recently used first
+func (c *ArchiveCache) List() ([]CacheEntry, error) {
+	dirs, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []CacheEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(c.Dir, dir.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache directory: %w", err)
+		}
+		for _, file := range files {
+			info, err := file.Info()
+			if err != nil || !info.Mode().IsRegular() || strings.HasSuffix(file.Name(), ".tmp") {
+				continue
+			}
+			entries = append(entries, CacheEntry{
+				Filename: file.Name(),
+				SHA256:   dir.Name(),
+				Path:     filepath.Join(c.Dir, dir.Name(), file.Name()),
+				Size:     info.Size(),
+				LastUsed: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.After(entries[j].LastUsed) })
+	return entries, nil
+}
+
+// Verify hashes every cached archive and reports whether it still matches
+// the checksum it is stored under
+func (c *ArchiveCache) Verify() ([]CacheEntry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		sum, err := hashFile(entries[i].Path)
+		valid := err == nil && strings.EqualFold(sum, entries[i].SHA256)
+		entries[i].Valid = &valid
+	}
+	return entries, nil
+}
+
+// Prune removes the least recently used archives until the cache holds at
+// most maxSize bytes, and returns the removed entries
+func (c *ArchiveCache) Prune(maxSize int64) ([]CacheEntry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	var removed []CacheEntry
+	for i := len(entries) - 1; i >= 0 && total > maxSize; i-- {
+		if err := c.Remove(entries[i]); err != nil {
+			return removed, err
+		}
+		total -= entries[i].Size
+		removed = append(removed, entries[i])
+	}
+	return removed, nil
+}
+
+// Remove deletes a cache entry and its checksum directory once it is empty
+func (c *ArchiveCache) Remove(entry CacheEntry) error {
+	if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s from the cache: %w", entry.Filename, err)
+	}
+	_ = os.Remove(filepath.Dir(entry.Path))
+	return nil
+}
+
+// RemoveVersion removes every cached archive, installer or source tarball of
+// a Go release
+func (c *ArchiveCache) RemoveVersion(version string) ([]CacheEntry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	prefix := "go" + strings.TrimPrefix(version, "go") + "."
+	var removed []CacheEntry
+	for _, e := range entries {
+		// go1.20.linux-amd64.tar.gz belongs to 1.20, go1.20.14.linux-amd64.tar.gz does not
+		rest, ok := strings.CutPrefix(e.Filename, prefix)
+		if !ok || rest == "" || rest[0] >= '0' && rest[0] <= '9' {
+			continue
+		}
+		if err := c.Remove(e); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
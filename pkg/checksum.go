@@ -7,43 +7,94 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync"
 )
 
-type DefaultChecksumCalculator struct{}
+// DefaultChecksumCalculator fetches the release index once and reuses it
+// for every lookup. The index is kept behind a pointer, so the calculator can
+// be copied like any value.
+type DefaultChecksumCalculator struct {
+	cache *releaseCache
+}
+
+// releaseCache holds a fetched release index, concurrent lookups wait for
+// the first fetch
+type releaseCache struct {
+	mu       sync.Mutex
+	releases []GoRelease
+}
+
+// cacheInit guards the lazy creation of each calculator's cache, it is not
+// held while the index is fetched
+var cacheInit sync.Mutex
+
+func (c *DefaultChecksumCalculator) releaseCache() *releaseCache {
+	cacheInit.Lock()
+	defer cacheInit.Unlock()
+	if c.cache == nil {
+		c.cache = &releaseCache{}
+	}
+	return c.cache
+}
 
 type GoRelease struct {
-	Version string `json:"version"`
-	Stable  bool   `json:"stable"`
-	Files   []struct {
-		Filename string `json:"filename"`
-		OS       string `json:"os"`
-		Arch     string `json:"arch"`
-		Version  string `json:"version"`
-		SHA256   string `json:"sha256"`
-	} `json:"files"`
+	Version string        `json:"version"`
+	Stable  bool          `json:"stable"`
+	Files   []ReleaseFile `json:"files"`
 }
 
-var URL = "https://go.dev/dl/?mode=json"
+// ReleaseFile is one downloadable file of a release. Kind is archive,
+// installer or source, OS and Arch are empty for the source tarball.
+type ReleaseFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Version  string `json:"version"`
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+}
+
+// URL is the release index, include=all lists every release and not only
+// the two supported ones
+var URL = "https://go.dev/dl/?mode=json&include=all"
+
+// Releases fetches the release index
+func (c *DefaultChecksumCalculator) Releases() ([]GoRelease, error) {
+	cache := c.releaseCache()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.releases != nil {
+		return cache.releases, nil
+	}
 
-func (c *DefaultChecksumCalculator) GetOfficialChecksum(filename string) (string, error) {
 	resp, err := http.Get(URL)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch Go releases: %w", err)
+		return nil, fmt.Errorf("failed to fetch Go releases: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch Go releases: HTTP status %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch Go releases: HTTP status %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	var releases []GoRelease
 	if err := json.Unmarshal(body, &releases); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	cache.releases = releases
+	return releases, nil
+}
+
+func (c *DefaultChecksumCalculator) GetOfficialChecksum(filename string) (string, error) {
+	releases, err := c.Releases()
+	if err != nil {
+		return "", err
 	}
 
 	for _, release := range releases {
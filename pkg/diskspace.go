@@ -0,0 +1,37 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+)
+
+// CheckWritable fails when files cannot be created in dir
+func CheckWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".automatedgo-write-check-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// CheckDiskSpace fails when the filesystem holding dir has less than needed
+// bytes available. The check is skipped on platforms where the free space
+// cannot be read.
+func CheckDiskSpace(dir string, needed int64) error {
+	if needed <= 0 {
+		return nil
+	}
+	available, supported, err := freeSpace(dir)
+	if !supported {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read free space of %s: %w", dir, err)
+	}
+	if available < uint64(needed) {
+		return fmt.Errorf("not enough free space in %s: %s needed, %s available", dir, FormatByteSize(needed), FormatByteSize(int64(available)))
+	}
+	return nil
+}
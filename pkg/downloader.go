@@ -1,14 +1,16 @@
 package pkg
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
-
-	"github.com/schollz/progressbar/v3"
 )
 
 var DownloadURLFormat = "https://dl.google.com/go/go%s.%s-%s.%s"
@@ -18,49 +20,118 @@ var validPlatforms = map[string][]string{
 	"darwin":  {"amd64", "arm64"},
 }
 
-type DefaultDownloader struct{}
+// DefaultDownloader keeps interrupted downloads on disk and resumes them
+// with HTTP Range requests, both across runs and for up to MaxRetries
+// automatic retries within one call. RateLimit, when set, caps the download
+// speed and may be shared between downloaders.
+type DefaultDownloader struct {
+	MaxRetries int
+	Progress   ProgressReporter
+	RateLimit  *RateLimiter
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.code)
+}
 
 func (d *DefaultDownloader) Download(url, filename string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("error downloading: %w", err)
+	_, err := d.DownloadWithHash(url, filename)
+	return err
+}
+
+// DownloadWithHash downloads the file and returns the hex encoded SHA-256 of
+// its content. When a partial file is resumed, the bytes already on disk are
+// hashed before the rest of the stream.
+func (d *DefaultDownloader) DownloadWithHash(url, filename string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		sum, err := d.download(url, filename)
+		var statusErr *statusError
+		if err == nil || attempt >= d.MaxRetries || errors.As(err, &statusErr) {
+			return sum, err
+		}
+		progressOrDefault(d.Progress).Retry(filepath.Base(filename), attempt+1, d.MaxRetries, err)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+func (d *DefaultDownloader) download(url, filename string) (string, error) {
+	state, offset := loadResumeState(url, filename)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("error downloading: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", state.validator())
 	}
 
-	out, err := os.Create(filename)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("error creating file: %w", err)
+		return "", fmt.Errorf("error downloading: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out *os.File
+	hash := sha256.New()
+	total := resp.ContentLength
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			clearResumeState(filename)
+			return "", fmt.Errorf("error downloading: unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		if err := hashPrefix(hash, filename, offset); err != nil {
+			return "", fmt.Errorf("error reading partial file: %w", err)
+		}
+		if out, err = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return "", fmt.Errorf("error opening partial file: %w", err)
+		}
+		total = size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// Either the file is already complete or the partial file is unusable
+		clearResumeState(filename)
+		if state.Size == offset {
+			return hashFile(filename)
+		}
+		if err := os.Remove(filename); err != nil {
+			return "", fmt.Errorf("error removing partial file: %w", err)
+		}
+		return d.download(url, filename)
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+		if out, err = os.Create(filename); err != nil {
+			return "", fmt.Errorf("error creating file: %w", err)
+		}
+		saveResumeState(filename, resumeState{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         resp.ContentLength,
+		})
+	default:
+		return "", &statusError{resp.StatusCode}
 	}
 	defer out.Close()
 
-	bar := progressbar.NewOptions64(
-		resp.ContentLength,
-		progressbar.OptionSetWidth(50),
-		progressbar.OptionSetDescription("Downloading:"),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "=",
-			SaucerHead:    ">",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
-		progressbar.OptionOnCompletion(func() {
-			fmt.Printf("\nDownload Complete!")
-		}),
-	)
-
-	_, err = io.Copy(io.MultiWriter(out, bar), resp.Body)
+	progress := progressOrDefault(d.Progress).Start(filepath.Base(filename), total, offset)
+	written, err := io.Copy(io.MultiWriter(out, hash, progressWriter{progress}), d.RateLimit.Reader(resp.Body))
 	if err != nil {
-		return fmt.Errorf("error saving file: %w", err)
+		err = fmt.Errorf("error saving file: %w", err)
+	} else if total > 0 && offset+written != total {
+		err = fmt.Errorf("error saving file: received %d of %d bytes", offset+written, total)
+	}
+	progress.Finish(err)
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	clearResumeState(filename)
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 type DefaultRemover struct{}
@@ -77,13 +148,21 @@ type DownloadConfig struct {
 	Downloader FileDownloader
 	Remover    FileRemover
 	Checksum   ChecksumCalculator
-	Input      io.Reader
-	Output     io.Writer
+	Cache      *ArchiveCache
+	// Verifier, when set, checks the archive after its checksum, such as its
+	// OpenPGP signature
+	Verifier Verifier
+	Kind     string
+	// Prompt asks for a missing target OS or architecture on Input instead
+	// of using the platform this program runs on
+	Prompt bool
+	Input  io.Reader
+	Output io.Writer
 }
 
-func promptForTargetOS(config *DownloadConfig) error {
+func promptForTargetOS(config *DownloadConfig, validOSes []string) error {
 	if config.TargetOS == "" {
-		fmt.Fprint(config.Output, "Enter target OS (windows, linux, darwin): ")
+		fmt.Fprintf(config.Output, "Enter target OS (%s): ", strings.Join(validOSes, ", "))
 		if _, err := fmt.Fscan(config.Input, &config.TargetOS); err != nil {
 			return fmt.Errorf("failed to read target OS: %w", err)
 		}
@@ -131,12 +210,20 @@ func fetchOfficialChecksum(config DownloadConfig, filename string) (string, erro
 	return checksum, nil
 }
 
-func downloadFile(config DownloadConfig, url string, filename string) error {
-	err := config.Downloader.Download(url, filename)
+// downloadFile returns the SHA-256 of the file when the downloader computes
+// it while downloading, and an empty string otherwise
+func downloadFile(config DownloadConfig, url string, filename string) (string, error) {
+	var sum string
+	var err error
+	if downloader, ok := config.Downloader.(ChecksumDownloader); ok {
+		sum, err = downloader.DownloadWithHash(url, filename)
+	} else {
+		err = config.Downloader.Download(url, filename)
+	}
 	if err != nil {
 		fmt.Fprintf(config.Output, "Error downloading file: %s\n", err)
 	}
-	return err
+	return sum, err
 }
 
 func handleChecksumMismatch(config DownloadConfig, filename string) {
@@ -145,11 +232,28 @@ func handleChecksumMismatch(config DownloadConfig, filename string) {
 	}
 }
 
-func verifyChecksum(config DownloadConfig, filename string, officialChecksum string) error {
-	fmt.Fprintf(config.Output, "\nCalculating checksum for %s\n", filename)
-	calculatedChecksum, err := config.Checksum.Calculate(filename)
+func removePartialDownload(config DownloadConfig, filename string) {
+	if removeErr := config.Remover.Remove(filename); removeErr != nil && !os.IsNotExist(removeErr) {
+		fmt.Fprintf(config.Output, "Error removing partial download %s: %s\n", filename, removeErr)
+	}
+}
+
+// verifyChecksum checks the downloaded file, which is still under its
+// temporary name, and reports it under the name it will be saved as. The
+// file is only read again when the download did not compute its checksum.
+func verifyChecksum(config DownloadConfig, downloaded, filename, officialChecksum, calculatedChecksum string) error {
+	var err error
+	// The checksum computed while downloading is SHA-256, a manifest may
+	// list SHA-512 checksums instead
+	if isSHA512(officialChecksum) {
+		calculatedChecksum = ""
+	}
+	if calculatedChecksum == "" {
+		fmt.Fprintf(config.Output, "\nCalculating checksum for %s\n", filename)
+		calculatedChecksum, err = config.Checksum.Calculate(downloaded)
+	}
 	if err != nil || calculatedChecksum != officialChecksum {
-		handleChecksumMismatch(config, filename)
+		handleChecksumMismatch(config, downloaded)
 		errMsg := fmt.Sprintf("Checksum mismatch: expected %s, got %s for file %s", officialChecksum, calculatedChecksum, filename)
 		fmt.Fprintln(config.Output, errMsg)
 		return errors.New(errMsg)
@@ -158,39 +262,193 @@ func verifyChecksum(config DownloadConfig, filename string, officialChecksum str
 	return nil
 }
 
-func DownloadGo(config DownloadConfig) error {
-	version := strings.TrimPrefix(config.Version, "go")
-	fmt.Fprintf(config.Output, "Preparing to download Go version %s\n", version)
+// runVerifier runs the optional verification stage that follows the
+// checksum check
+func runVerifier(config DownloadConfig, downloaded, filename, url string) error {
+	if config.Verifier == nil {
+		return nil
+	}
+	fmt.Fprintf(config.Output, "Verifying signature of %s\n", filename)
+	if err := config.Verifier.Verify(downloaded, url); err != nil {
+		fmt.Fprintf(config.Output, "Signature verification failed for %s: %s\n", filename, err)
+		return fmt.Errorf("error verifying %s: %w", filename, err)
+	}
+	fmt.Fprintln(config.Output, "Signature verification successful!")
+	return nil
+}
+
+// partialFilename is where an archive is written until it has been verified
+func partialFilename(filename string) string {
+	return filename + ".part"
+}
 
-	if err := promptForTargetOS(&config); err != nil {
+// useHostPlatform fills in the parts of the target platform that are not set
+// with the platform this program runs on
+func useHostPlatform(config *DownloadConfig) {
+	if config.TargetOS != "" && config.Arch != "" {
+		return
+	}
+	host := HostPlatform()
+	if config.TargetOS == "" {
+		config.TargetOS = host.OS
+	}
+	if config.Arch == "" {
+		config.Arch = NormalizeArch(config.TargetOS, runtime.GOARCH)
+	}
+	fmt.Fprintf(config.Output, "Using platform %s/%s detected from this machine\n", NormalizeOS(config.TargetOS), config.Arch)
+}
+
+// selectPlatform fills in or prompts for the target platform when it is not
+// set and validates it against the platforms published for the version
+func selectPlatform(config *DownloadConfig, version, kind string) error {
+	if !config.Prompt {
+		useHostPlatform(config)
+	}
+	platforms := platformsFor(*config, version, kind)
+	if err := promptForTargetOS(config, sortedKeys(platforms)); err != nil {
 		return err
 	}
+	config.TargetOS = NormalizeOS(config.TargetOS)
 
-	validArchs, ok := validPlatforms[config.TargetOS]
+	validArchs, ok := platforms[config.TargetOS]
 	if !ok {
-		return fmt.Errorf("unsupported operating system: %s", config.TargetOS)
+		return unsupportedOSError(config.TargetOS, version, platforms)
 	}
 
-	if err := promptForArchitecture(&config, validArchs); err != nil {
+	if err := promptForArchitecture(config, validArchs); err != nil {
 		return err
 	}
+	config.Arch = NormalizeArch(config.TargetOS, config.Arch)
 
 	if !isValidArchitecture(config.Arch, validArchs) {
-		return fmt.Errorf("unsupported architecture %s for OS %s", config.Arch, config.TargetOS)
+		return unsupportedArchError(config.Arch, config.TargetOS, version, platforms)
 	}
+	return nil
+}
 
-	filename := getFilename(version, config)
-	fmt.Fprintf(config.Output, "Fetching Official Checksum for %s\n", filename)
+// locateFile returns the release file to download, with its official
+// checksum and, when the release index lists it, its size, and the URL to
+// download it from
+func locateFile(config DownloadConfig, version, kind string) (ReleaseFile, string, error) {
+	if kind == KindArchive {
+		filename := getFilename(version, config)
+		fmt.Fprintf(config.Output, "Fetching Official Checksum for %s\n", filename)
+		officialChecksum, err := fetchOfficialChecksum(config, filename)
+		if err != nil {
+			return ReleaseFile{}, "", err
+		}
+		file := ReleaseFile{Filename: filename, OS: config.TargetOS, Arch: config.Arch, SHA256: officialChecksum, Kind: kind}
+		// The size is only used for the disk space check, which is skipped
+		// when the release index is not available
+		if listed, err := resolveReleaseFile(config, version, kind); err == nil && listed.Filename == filename {
+			file.Size = listed.Size
+		}
+		url := fmt.Sprintf(DownloadURLFormat, version, config.TargetOS, config.Arch, getExtension(config.TargetOS))
+		return file, url, nil
+	}
 
-	officialChecksum, err := fetchOfficialChecksum(config, filename)
+	fmt.Fprintf(config.Output, "Looking up the %s for Go %s in the release index\n", kind, version)
+	file, err := resolveReleaseFile(config, version, kind)
 	if err != nil {
+		return ReleaseFile{}, "", err
+	}
+	fmt.Fprintf(config.Output, "Found %s with official checksum %s\n", file.Filename, file.SHA256)
+	return file, DownloadBaseURL + file.Filename, nil
+}
+
+// checkDestination fails early when the download directory is not writable
+// or does not have room for what is left to download
+func checkDestination(dir string, file ReleaseFile, partial string) error {
+	if dir == "" {
+		dir = "."
+	}
+	if err := CheckWritable(dir); err != nil {
 		return err
 	}
+	needed := file.Size
+	if info, err := os.Stat(partial); err == nil {
+		needed -= info.Size()
+	}
+	return CheckDiskSpace(dir, needed)
+}
 
-	url := fmt.Sprintf(DownloadURLFormat, version, config.TargetOS, config.Arch, getExtension(config.TargetOS))
-	if err = downloadFile(config, url, filename); err != nil {
+func DownloadGo(config DownloadConfig) error {
+	version := strings.TrimPrefix(config.Version, "go")
+	kind, err := ParseFileKind(config.Kind)
+	if err != nil {
 		return err
 	}
+	fmt.Fprintf(config.Output, "Preparing to download Go version %s\n", version)
+
+	// The source tarball is the same for every platform
+	if kind != KindSource {
+		if err := selectPlatform(&config, version, kind); err != nil {
+			return err
+		}
+	}
+
+	if config.Path != "" {
+		if err := os.MkdirAll(config.Path, 0o755); err != nil {
+			return fmt.Errorf("error creating download directory: %w", err)
+		}
+	}
 
-	return verifyChecksum(config, filename, officialChecksum)
+	file, url, err := locateFile(config, version, kind)
+	if err != nil {
+		return err
+	}
+	filename, officialChecksum := file.Filename, file.SHA256
+
+	dest := filepath.Join(config.Path, filename)
+	// The cache is keyed by SHA-256 checksums
+	cache := config.Cache
+	if isSHA512(officialChecksum) {
+		cache = nil
+	}
+	if cache != nil {
+		if cached, ok := cache.Lookup(filename, officialChecksum); ok {
+			fmt.Fprintf(config.Output, "Using cached archive %s, checksum verified\n", cached)
+			if err := runVerifier(config, cached, filename, url); err != nil {
+				return err
+			}
+			if err := cache.CopyTo(cached, dest); err != nil {
+				return fmt.Errorf("error copying cached archive: %w", err)
+			}
+			fmt.Fprintf(config.Output, "Saved %s\n", dest)
+			return nil
+		}
+	}
+
+	partial := partialFilename(dest)
+	if err := checkDestination(config.Path, file, partial); err != nil {
+		return err
+	}
+	calculatedChecksum, err := downloadFile(config, url, partial)
+	if err != nil {
+		if _, statErr := os.Stat(partial); statErr == nil {
+			fmt.Fprintf(config.Output, "The partial download was kept in %s, run the download again to resume it\n", partial)
+		}
+		return err
+	}
+
+	if err = verifyChecksum(config, partial, filename, officialChecksum, calculatedChecksum); err != nil {
+		return err
+	}
+	if err = runVerifier(config, partial, filename, url); err != nil {
+		removePartialDownload(config, partial)
+		return err
+	}
+
+	if err = os.Rename(partial, dest); err != nil {
+		removePartialDownload(config, partial)
+		return fmt.Errorf("error moving verified download into place: %w", err)
+	}
+	fmt.Fprintf(config.Output, "Saved %s\n", dest)
+
+	if cache != nil {
+		if err := cache.Store(dest, filename, officialChecksum); err != nil {
+			fmt.Fprintf(config.Output, "Warning: %s\n", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExplainVersion writes every extraction rule tried on content, what it
+// matched and which rule won, and returns the extraction result
+func ExplainVersion(w io.Writer, source string, content []byte, opts ExtractOptions) (VersionMatch, error) {
+	mode := "first matching rule wins"
+	if opts.Strict {
+		mode = "strict mode"
+	}
+	fmt.Fprintf(w, "Detecting Go version in %s (%s)\n\n", source, mode)
+
+	step := 0
+	opts.Trace = func(s TraceStep) {
+		step++
+		fmt.Fprintf(w, "%d. %s [%s confidence]\n", step, s.Rule, s.Confidence)
+		fmt.Fprintf(w, "   looks for %s\n", s.Description)
+		if len(s.Matches) == 0 {
+			fmt.Fprintln(w, "   no match")
+			return
+		}
+		fmt.Fprintf(w, "   matched %s: %s\n", strings.Join(s.Matches, ", "), s.Reason)
+	}
+
+	match, err := ExtractGoVersionMatch(string(content), opts)
+	fmt.Fprintln(w)
+	if err != nil {
+		fmt.Fprintf(w, "Result: %s\n", err)
+		return match, err
+	}
+	fmt.Fprintf(w, "Result: %s from rule %q (%s confidence)\n", match.Version, match.Rule, match.Confidence)
+	return match, nil
+}
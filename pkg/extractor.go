@@ -1,17 +1,105 @@
 package pkg
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 )
 
+// Confidence describes how likely it is that a matched string really is a Go version
+type Confidence int
+
+const (
+	ConfidenceLow Confidence = iota
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceHigh:
+		return "high"
+	case ConfidenceMedium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// VersionMatch is a single Go version candidate along with the rule that produced it
+type VersionMatch struct {
+	Version    string
+	Rule       string
+	Confidence Confidence
+}
+
+// ExtractOptions controls how a Go version is selected from file content.
+// In strict mode low-confidence matches are rejected and disagreeing
+// candidates of the same confidence result in an AmbiguousVersionError.
+// Trace, when set, is called once per rule with what the rule matched and
+// why it was or was not used.
+type ExtractOptions struct {
+	Strict bool
+	Trace  func(TraceStep)
+}
+
+type TraceStep struct {
+	Rule        string
+	Description string
+	Confidence  Confidence
+	Matches     []string
+	Selected    bool
+	Reason      string
+}
+
+type AmbiguousVersionError struct {
+	Candidates []VersionMatch
+}
+
+func (e *AmbiguousVersionError) Error() string {
+	return "ambiguous Go version, candidates: " + formatCandidates(e.Candidates)
+}
+
+var ErrNoVersionFound = errors.New("unable to extract Go version")
+
+const versionPattern = `\d+\.\d+(?:\.\d+)?`
+
+var jsonVersionKeys = []string{"go_version", "goVersion", "golang_version", "golangVersion", "GO_VERSION"}
+
+type extractionRule struct {
+	name        string
+	description string
+	confidence  Confidence
+	find        func(content string) []string
+}
+
+// Rules are tried in order, the first rule that matches wins in non-strict mode.
+// They are sorted by confidence, so a lower confidence match never wins over a
+// higher one and the order only breaks ties.
+var extractionRules = []extractionRule{
+	{"JSON key", "a " + strings.Join(jsonVersionKeys, ", ") + " key in a JSON document", ConfidenceHigh, findJSONVersions},
+	regexRule("go.mod directive", ConfidenceHigh, `(?m)^go\s+(`+versionPattern+`(?:(?:rc|beta)\d+)?)\s*(?://.*)?$`),
+	regexRule("Dockerfile FROM golang", ConfidenceHigh, `(?i)FROM\s+golang:(`+versionPattern+`)`),
+	regexRule("Dockerfile ARG GO_VERSION", ConfidenceHigh, `(?i)ARG\s+GO_VERSION=(`+versionPattern+`)`),
+	regexRule("Dockerfile ENV GO_VERSION", ConfidenceHigh, `(?i)ENV\s+GO_VERSION=(`+versionPattern+`)`),
+	// The key must be a word of its own, such as go: 1.22 or go1.22, so mongo:4.4 is not a Go version
+	regexRule("go version key", ConfidenceMedium, `(?i)\b(?:(?:go|golang|go_version|golang_version)\b(?:\s*version)?\s*[:=]?\s*v?|go)(`+versionPattern+`)`),
+	regexRule("bare version number", ConfidenceLow, `(`+versionPattern+`)`),
+}
+
 func GetCurrentVersion(filePath, directVersion string) (string, error) {
+	return GetCurrentVersionWithOptions(filePath, directVersion, ExtractOptions{})
+}
+
+func GetCurrentVersionWithOptions(filePath, directVersion string, opts ExtractOptions) (string, error) {
 	if filePath != "" {
-		return ReadVersionFromFile(filePath)
+		return ReadVersionFromFileWithOptions(filePath, opts)
 	}
 	if directVersion != "" {
 		return directVersion, nil
@@ -20,56 +108,230 @@ func GetCurrentVersion(filePath, directVersion string) (string, error) {
 }
 
 func ReadVersionFromFile(filePath string) (string, error) {
+	return ReadVersionFromFileWithOptions(filePath, ExtractOptions{})
+}
+
+// ReadVersionFromFileWithOptions reads the file once, so pipes and process
+// substitution work, and detects compiled binaries from the content
+func ReadVersionFromFileWithOptions(filePath string, opts ExtractOptions) (string, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", err
 	}
+	return versionFromContent(filePath, content, opts)
+}
 
-	version := ExtractGoVersion(string(content))
-	if version != "" {
-		return version, nil
+func ReadVersionFromReader(r io.Reader, opts ExtractOptions) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read version input: %w", err)
 	}
+	return versionFromContent(StdinSource, content, opts)
+}
 
+func versionFromContent(source string, content []byte, opts ExtractOptions) (string, error) {
+	if IsExecutableHeader(content) {
+		info, err := ReadBinaryInfoFrom(bytes.NewReader(content), source)
+		if err != nil {
+			return "", err
+		}
+		return info.GoVersion, nil
+	}
+
+	match, err := ExtractGoVersionMatch(string(content), opts)
+	if err == nil {
+		return match.Version, nil
+	}
+
+	var ambiguous *AmbiguousVersionError
+	if errors.As(err, &ambiguous) {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+	if opts.Strict {
+		return "", fmt.Errorf("unable to extract Go version from file: %w", err)
+	}
 	return "", errors.New("unable to extract Go version from file")
 }
 
 func ExtractGoVersion(content string) string {
-	// Common patterns for Go version
-	patterns := []string{
-		`(?i)(?:go|golang|go_version|golang_version)(?:\s*version)?[:=]?\s*v?(\d+\.\d+(?:\.\d+)?)`,
-		`(?i)FROM\s+golang:(\d+\.\d+(?:\.\d+)?)`,
-		`(?i)ARG\s+GO_VERSION=(\d+\.\d+(?:\.\d+)?)`,
-		`(?i)ENV\s+GO_VERSION=(\d+\.\d+(?:\.\d+)?)`,
-		`(\d+\.\d+(?:\.\d+)?)`,
-	}
-
-	// Check for JSON format
-	var jsonData map[string]interface{}
-	if err := json.Unmarshal([]byte(content), &jsonData); err == nil {
-		for _, key := range []string{"go_version", "goVersion", "golang_version", "golangVersion", "GO_VERSION"} {
-			if version, ok := jsonData[key].(string); ok {
-				return version
+	match, err := ExtractGoVersionMatch(content, ExtractOptions{})
+	if err != nil {
+		return ""
+	}
+	return match.Version
+}
+
+// ExtractGoVersionCandidates returns every match of every rule, in rule order
+func ExtractGoVersionCandidates(content string) []VersionMatch {
+	var candidates []VersionMatch
+	for _, rule := range extractionRules {
+		for _, version := range rule.find(content) {
+			candidates = append(candidates, VersionMatch{Version: version, Rule: rule.name, Confidence: rule.confidence})
+		}
+	}
+	return candidates
+}
+
+func ExtractGoVersionMatch(content string, opts ExtractOptions) (VersionMatch, error) {
+	evaluateAll := opts.Strict || opts.Trace != nil
+	found := make([][]string, len(extractionRules))
+	for i, rule := range extractionRules {
+		found[i] = rule.find(content)
+		if len(found[i]) > 0 && !evaluateAll {
+			break
+		}
+	}
+
+	match, winner, err := selectMatch(found, opts.Strict)
+	if opts.Trace != nil {
+		traceRules(found, winner, err, opts)
+	}
+	return match, err
+}
+
+// selectMatch picks the winning match and returns the index of its rule, or -1
+func selectMatch(found [][]string, strict bool) (VersionMatch, int, error) {
+	if !strict {
+		for i, versions := range found {
+			if len(versions) > 0 {
+				rule := extractionRules[i]
+				return VersionMatch{Version: versions[0], Rule: rule.name, Confidence: rule.confidence}, i, nil
 			}
 		}
+		return VersionMatch{}, -1, ErrNoVersionFound
+	}
+
+	var candidates []VersionMatch
+	var ruleIndex []int
+	best := ConfidenceLow
+	for i, versions := range found {
+		rule := extractionRules[i]
+		for _, version := range versions {
+			candidates = append(candidates, VersionMatch{Version: version, Rule: rule.name, Confidence: rule.confidence})
+			ruleIndex = append(ruleIndex, i)
+		}
+		if len(versions) > 0 && rule.confidence > best {
+			best = rule.confidence
+		}
+	}
+
+	if best == ConfidenceLow {
+		if len(candidates) > 0 {
+			return VersionMatch{}, -1, fmt.Errorf("%w: only low-confidence candidates found: %s", ErrNoVersionFound, formatCandidates(candidates))
+		}
+		return VersionMatch{}, -1, ErrNoVersionFound
+	}
+
+	var top []VersionMatch
+	winner := -1
+	seen := make(map[string]bool)
+	for i, c := range candidates {
+		if c.Confidence == best && !seen[c.Version] {
+			seen[c.Version] = true
+			top = append(top, c)
+			if winner == -1 {
+				winner = ruleIndex[i]
+			}
+		}
+	}
+	if len(top) > 1 {
+		return VersionMatch{}, -1, &AmbiguousVersionError{Candidates: top}
+	}
+	return top[0], winner, nil
+}
+
+func traceRules(found [][]string, winner int, err error, opts ExtractOptions) {
+	var ambiguous *AmbiguousVersionError
+	isAmbiguous := errors.As(err, &ambiguous)
+	best := ConfidenceLow
+	for i, versions := range found {
+		if len(versions) > 0 && extractionRules[i].confidence > best {
+			best = extractionRules[i].confidence
+		}
 	}
 
-	// Check for go.mod file
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "go ") {
-			return strings.TrimSpace(strings.TrimPrefix(line, "go "))
+	for i, rule := range extractionRules {
+		step := TraceStep{
+			Rule:        rule.name,
+			Description: rule.description,
+			Confidence:  rule.confidence,
+			Matches:     found[i],
+			Selected:    i == winner,
+		}
+		switch {
+		case step.Selected:
+			step.Reason = "selected"
+		case len(found[i]) == 0:
+			step.Reason = "no match"
+		case !opts.Strict && winner >= 0 && i > winner:
+			step.Reason = "ignored, an earlier rule already matched"
+		case opts.Strict && rule.confidence == ConfidenceLow:
+			step.Reason = "rejected, low confidence matches are not accepted in strict mode"
+		case opts.Strict && rule.confidence < best:
+			step.Reason = "ignored, a higher confidence rule matched"
+		case isAmbiguous:
+			step.Reason = "conflicts with other candidates of the same confidence"
+		default:
+			step.Reason = "agrees with the selected match"
 		}
+		opts.Trace(step)
 	}
+}
+
+func formatCandidates(candidates []VersionMatch) string {
+	parts := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		parts = append(parts, fmt.Sprintf("%s (%s, %s confidence)", c.Version, c.Rule, c.Confidence))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func regexRule(name string, confidence Confidence, pattern string) extractionRule {
+	return extractionRule{name, "the pattern " + pattern, confidence, regexFinder(pattern)}
+}
 
-	// Check for other formats using regex
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindStringSubmatch(content)
-		if len(matches) > 1 {
-			return matches[1]
+func regexFinder(pattern string) func(string) []string {
+	re := regexp.MustCompile(pattern)
+	return func(content string) []string {
+		var versions []string
+		for _, matches := range re.FindAllStringSubmatch(content, -1) {
+			versions = append(versions, matches[1])
 		}
+		return versions
 	}
+}
 
-	return ""
+func findJSONVersions(content string) []string {
+	var jsonData interface{}
+	if err := json.Unmarshal([]byte(content), &jsonData); err != nil {
+		return nil
+	}
+	var versions []string
+	collectJSONVersions(jsonData, &versions)
+	return versions
+}
+
+// collectJSONVersions checks the known keys of an object before descending
+// into nested values, so top-level keys keep precedence over nested ones
+func collectJSONVersions(value interface{}, versions *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range jsonVersionKeys {
+			if version, ok := v[key].(string); ok {
+				*versions = append(*versions, version)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectJSONVersions(v[key], versions)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectJSONVersions(item, versions)
+		}
+	}
 }
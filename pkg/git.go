@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var GitCommand = "git"
+
+// GitRevisionSource reads files from a revision in the local git object
+// store, without touching the working tree
+type GitRevisionSource struct {
+	Repo string
+	Rev  string
+}
+
+func NewGitRevisionSource(repo, rev string) (*GitRevisionSource, error) {
+	commit, err := runGit(repo, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+	return &GitRevisionSource{Repo: repo, Rev: strings.TrimSpace(string(commit))}, nil
+}
+
+func (g *GitRevisionSource) ListFiles() ([]string, error) {
+	out, err := runGit(g.Repo, "ls-tree", "-r", "-z", "--name-only", g.Rev)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+func (g *GitRevisionSource) ReadFile(name string) ([]byte, error) {
+	return runGit(g.Repo, "cat-file", "blob", g.Rev+":"+name)
+}
+
+func runGit(repo string, args ...string) ([]byte, error) {
+	if repo != "" {
+		args = append([]string{"-C", repo}, args...)
+	}
+	cmd := exec.Command(GitCommand, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
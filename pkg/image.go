@@ -0,0 +1,301 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Binaries larger than this are skipped when scanning image layers, they
+// have to be held in memory to read their build info
+const maxImageBinarySize = 512 << 20
+
+const maxImageMetadataSize = 4 << 20
+
+type dockerManifest struct {
+	Layers []string `json:"Layers"`
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+type ociIndex struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type layerContent struct {
+	binaries  map[string]BinaryInfo
+	replaced  []string
+	whiteouts []string
+	opaque    []string
+}
+
+// ScanImageTarball finds the Go executables in a `docker save` or OCI layout
+// tarball. Layers are applied in order, so files removed or replaced by a
+// later layer are not reported.
+func ScanImageTarball(tarball string) ([]BinaryInfo, error) {
+	metadata, err := readImageMetadata(tarball)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := imageLayers(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := readImageLayers(tarball, layers)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]BinaryInfo)
+	for _, layer := range layers {
+		content, ok := contents[layer]
+		if !ok {
+			return nil, fmt.Errorf("layer %s not found in %s", layer, tarball)
+		}
+		for _, dir := range content.opaque {
+			removeUnder(files, dir)
+		}
+		for _, p := range content.whiteouts {
+			delete(files, p)
+			removeUnder(files, p)
+		}
+		for _, p := range content.replaced {
+			delete(files, p)
+		}
+		for p, info := range content.binaries {
+			files[p] = info
+		}
+	}
+
+	infos := make([]BinaryInfo, 0, len(files))
+	for _, info := range files {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos, nil
+}
+
+func removeUnder(files map[string]BinaryInfo, dir string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for p := range files {
+		if strings.HasPrefix(p, prefix) {
+			delete(files, p)
+		}
+	}
+}
+
+func readImageMetadata(tarball string) (map[string][]byte, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	metadata := make(map[string][]byte)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image tarball: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || (name != "manifest.json" && name != "index.json" && hdr.Size > maxImageMetadataSize) {
+			continue
+		}
+		// Blobs are only kept when they look like JSON, layers are read in a second pass
+		data, err := io.ReadAll(io.LimitReader(tr, maxImageMetadataSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if json.Valid(data) {
+			metadata[name] = data
+		}
+	}
+	return metadata, nil
+}
+
+func imageLayers(metadata map[string][]byte) ([]string, error) {
+	if data, ok := metadata["manifest.json"]; ok {
+		var manifests []dockerManifest
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest.json: %w", err)
+		}
+		if len(manifests) == 0 {
+			return nil, errors.New("manifest.json does not list any image")
+		}
+		layers := make([]string, 0, len(manifests[0].Layers))
+		for _, layer := range manifests[0].Layers {
+			layers = append(layers, path.Clean(layer))
+		}
+		return layers, nil
+	}
+
+	data, ok := metadata["index.json"]
+	if !ok {
+		return nil, errors.New("not an image tarball: neither manifest.json nor index.json found")
+	}
+	for depth := 0; depth < 4; depth++ {
+		var index ociIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("failed to parse OCI index: %w", err)
+		}
+		if len(index.Layers) > 0 {
+			layers := make([]string, 0, len(index.Layers))
+			for _, layer := range index.Layers {
+				layers = append(layers, blobPath(layer.Digest))
+			}
+			return layers, nil
+		}
+		if len(index.Manifests) == 0 {
+			return nil, errors.New("OCI index does not list any manifest")
+		}
+		next := pickManifest(index.Manifests)
+		if data, ok = metadata[blobPath(next.Digest)]; !ok {
+			return nil, fmt.Errorf("manifest %s not found in image tarball", next.Digest)
+		}
+	}
+	return nil, errors.New("OCI index nesting is too deep")
+}
+
+// pickManifest prefers the manifest for the platform this tool runs on
+func pickManifest(manifests []ociDescriptor) ociDescriptor {
+	for _, m := range manifests {
+		if m.Platform != nil && m.Platform.OS == runtime.GOOS && m.Platform.Architecture == runtime.GOARCH {
+			return m
+		}
+	}
+	for _, m := range manifests {
+		if m.Platform == nil || m.Platform.OS != "unknown" {
+			return m
+		}
+	}
+	return manifests[0]
+}
+
+func blobPath(digest string) string {
+	return path.Join("blobs", strings.Replace(digest, ":", "/", 1))
+}
+
+func readImageLayers(tarball string, layers []string) (map[string]*layerContent, error) {
+	wanted := make(map[string]bool, len(layers))
+	for _, layer := range layers {
+		wanted[layer] = true
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	contents := make(map[string]*layerContent)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image tarball: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if !wanted[name] || contents[name] != nil {
+			continue
+		}
+		content, err := scanLayer(tr, name)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", name, err)
+		}
+		contents[name] = content
+	}
+	return contents, nil
+}
+
+func scanLayer(r io.Reader, layer string) (*layerContent, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	var stream io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		stream = gz
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, errors.New("zstd compressed layers are not supported")
+	}
+
+	content := &layerContent{binaries: make(map[string]BinaryInfo)}
+	tr := tar.NewReader(stream)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return content, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean("/" + hdr.Name)
+		dir, base := path.Split(name)
+		switch {
+		case base == ".wh..wh..opq":
+			content.opaque = append(content.opaque, path.Clean(dir))
+			continue
+		case strings.HasPrefix(base, ".wh."):
+			content.whiteouts = append(content.whiteouts, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+			continue
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		// A file replaced by a non-binary in this layer hides the lower one
+		content.replaced = append(content.replaced, name)
+		if hdr.Typeflag != tar.TypeReg || hdr.Size > maxImageBinarySize {
+			continue
+		}
+
+		header := make([]byte, 4)
+		n, _ := io.ReadFull(tr, header)
+		if !bytes.Equal(header[:n], []byte("\x7fELF")) {
+			continue
+		}
+		rest, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		data := append(header[:n], rest...)
+		info, err := ReadBinaryInfoFrom(bytes.NewReader(data), name)
+		if err != nil {
+			continue
+		}
+		info.Layer = layer
+		content.binaries[name] = *info
+	}
+}
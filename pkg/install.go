@@ -0,0 +1,458 @@
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Install downloads and verifies the archive of a Go release for this machine
+// and installs it into goroot, replacing any previous installation
+func Install(config DownloadConfig, goroot string) error {
+	version := strings.TrimPrefix(config.Version, "go")
+	host := HostPlatform()
+	config.Version, config.TargetOS, config.Arch, config.Kind = version, host.OS, host.Arch, KindArchive
+
+	// Fail before downloading when goroot cannot be written or the archive
+	// alone would not fit, the extracted size is checked once it is known
+	parent := filepath.Dir(filepath.Clean(goroot))
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return fmt.Errorf("error creating installation directory: %w", err)
+	}
+	if err := CheckWritable(parent); err != nil {
+		return err
+	}
+	if file, err := resolveReleaseFile(config, version, KindArchive); err == nil {
+		if err := CheckDiskSpace(parent, file.Size); err != nil {
+			return err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "automatedgo-install-*")
+	if err != nil {
+		return fmt.Errorf("error creating download directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	config.Path = dir
+
+	if err := DownloadGo(config); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(config.Output, "Installing Go %s into %s\n", version, goroot)
+	if err := InstallArchive(filepath.Join(dir, getFilename(version, config)), goroot, version); err != nil {
+		return err
+	}
+	fmt.Fprintf(config.Output, "Installed Go %s in %s\n", version, goroot)
+	return nil
+}
+
+// InstallArchive extracts a verified .tar.gz or .zip Go archive into goroot.
+// The archive is extracted next to goroot first and only swapped in once its
+// VERSION file matches version, so a failed install leaves the previous
+// installation untouched.
+func InstallArchive(archive, goroot, version string) error {
+	goroot = filepath.Clean(goroot)
+	parent := filepath.Dir(goroot)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return fmt.Errorf("error creating installation directory: %w", err)
+	}
+	if err := CheckWritable(parent); err != nil {
+		return err
+	}
+	size, err := extractedSize(archive)
+	if err != nil {
+		return err
+	}
+	if err := CheckDiskSpace(parent, size); err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(goroot)+".new-*")
+	if err != nil {
+		return fmt.Errorf("error creating installation directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractArchive(archive, staging); err != nil {
+		return fmt.Errorf("error extracting %s: %w", filepath.Base(archive), err)
+	}
+	installed, err := ReadGOROOTVersion(staging)
+	if err != nil {
+		return err
+	}
+	if want := strings.TrimPrefix(version, "go"); installed != want {
+		return fmt.Errorf("archive %s contains Go %s, expected %s", filepath.Base(archive), installed, want)
+	}
+	// MkdirTemp creates the directory only accessible to its owner
+	if err := os.Chmod(staging, 0o755); err != nil {
+		return err
+	}
+	return replaceDir(staging, goroot)
+}
+
+// replaceDir moves src to dest. An existing dest is moved aside first and put
+// back when src cannot be moved into place.
+func replaceDir(src, dest string) error {
+	old := ""
+	if _, err := os.Lstat(dest); err == nil {
+		old = src + ".old"
+		if err := os.Rename(dest, old); err != nil {
+			return fmt.Errorf("error replacing %s: %w", dest, err)
+		}
+	}
+	if err := os.Rename(src, dest); err != nil {
+		if old != "" {
+			_ = os.Rename(old, dest)
+		}
+		return fmt.Errorf("error replacing %s: %w", dest, err)
+	}
+	if old != "" {
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("installed %s but failed to remove the previous installation: %w", dest, err)
+		}
+	}
+	return nil
+}
+
+// extractedSize estimates the space an archive takes once extracted, from
+// the zip directory or from the size trailer of a gzip stream
+func extractedSize(archive string) (int64, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		r, err := zip.OpenReader(archive)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		var size int64
+		for _, f := range r.File {
+			size += int64(f.UncompressedSize64)
+		}
+		return size, nil
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() < 4 {
+		return 0, fmt.Errorf("%s is not a gzip archive", filepath.Base(archive))
+	}
+	// The last four bytes hold the uncompressed size modulo 4 GiB, which is
+	// enough for Go releases
+	var trailer [4]byte
+	if _, err := f.ReadAt(trailer[:], info.Size()-4); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint32(trailer[:])), nil
+}
+
+func extractArchive(archive, dest string) error {
+	e := &extractor{root: dest, symlinks: make(map[string]string)}
+	var err error
+	switch {
+	case strings.HasSuffix(archive, ".zip"):
+		err = e.extractZip(archive)
+	case strings.HasSuffix(archive, ".tar.gz"):
+		err = e.extractTarGz(archive)
+	default:
+		return fmt.Errorf("unsupported archive format, must be .tar.gz or .zip")
+	}
+	if err != nil {
+		return err
+	}
+	return e.finish()
+}
+
+// extractor writes the entries of a Go archive below root. Entry names must
+// stay inside the go/ directory of the archive, symlinks must point inside
+// it, and nothing is written through a symlink created by the archive.
+type extractor struct {
+	root string
+	// symlinks maps the links created so far to their targets
+	symlinks map[string]string
+	dirs     []dirMode
+}
+
+type dirMode struct {
+	path    string
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// target maps an archive entry such as go/bin/go to its path below root. It
+// returns "" for the go/ directory itself.
+func (e *extractor) target(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	top, rel, _ := strings.Cut(clean, "/")
+	if top != "go" {
+		return "", fmt.Errorf("unexpected entry %q outside the go directory", name)
+	}
+	if rel == "" {
+		return "", nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(rel)) || strings.Contains(rel, `\`) {
+		return "", fmt.Errorf("unsafe path %q", name)
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if _, ok := e.symlinks[dir]; ok {
+			return "", fmt.Errorf("entry %q is inside a symlink", name)
+		}
+	}
+	return rel, nil
+}
+
+func (e *extractor) mkdir(name string, mode fs.FileMode, modTime time.Time) error {
+	rel, err := e.target(name)
+	if err != nil || rel == "" {
+		return err
+	}
+	dir := filepath.Join(e.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// Permissions are applied at the end so read-only directories can still
+	// be filled
+	e.dirs = append(e.dirs, dirMode{path: dir, mode: mode.Perm(), modTime: modTime})
+	return nil
+}
+
+func (e *extractor) writeFile(name string, mode fs.FileMode, modTime time.Time, r io.Reader) error {
+	rel, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if rel == "" {
+		return fmt.Errorf("unexpected file entry %q", name)
+	}
+	file := filepath.Join(e.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	// O_EXCL refuses to follow a symlink or overwrite an earlier entry
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file, mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(file, modTime, modTime)
+}
+
+func (e *extractor) symlink(name, linkTarget string) error {
+	rel, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if rel == "" {
+		return fmt.Errorf("unexpected symlink entry %q", name)
+	}
+	// The target is not cleaned as text, a ".." after an earlier symlink
+	// leaves the directory that symlink points at
+	if filepath.IsAbs(linkTarget) || path.IsAbs(filepath.ToSlash(linkTarget)) || !e.inside(path.Dir(rel)+"/"+filepath.ToSlash(linkTarget)) {
+		return fmt.Errorf("symlink %q points outside the installation: %q", name, linkTarget)
+	}
+	link := filepath.Join(e.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+		return err
+	}
+	if err := os.Symlink(linkTarget, link); err != nil {
+		return err
+	}
+	e.symlinks[rel] = linkTarget
+	return nil
+}
+
+// inside resolves rel, a slash separated path below root, one component at a
+// time, following the symlinks already on disk the way the kernel would, and
+// reports whether it stays below root. Components that do not exist yet are
+// taken as they are.
+func (e *extractor) inside(rel string) bool {
+	var resolved []string
+	pending := strings.Split(rel, "/")
+	for links := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return false
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		target, err := os.Readlink(filepath.Join(e.root, filepath.FromSlash(path.Join(append(resolved, part)...))))
+		if err != nil {
+			resolved = append(resolved, part)
+			continue
+		}
+		// Give up on symlink loops, like the kernel does
+		if links++; links > 255 || filepath.IsAbs(target) || path.IsAbs(filepath.ToSlash(target)) {
+			return false
+		}
+		pending = append(strings.Split(filepath.ToSlash(target), "/"), pending...)
+	}
+	return true
+}
+
+// checkSymlinks checks every symlink again once all entries are written, as
+// a later entry can turn a directory an earlier link passes through into a
+// symlink
+func (e *extractor) checkSymlinks() error {
+	root, err := filepath.EvalSymlinks(e.root)
+	if err != nil {
+		return err
+	}
+	for rel, linkTarget := range e.symlinks {
+		escapes := !e.inside(rel)
+		if !escapes {
+			// Links to files that do not exist cannot be evaluated
+			if resolved, err := filepath.EvalSymlinks(filepath.Join(e.root, filepath.FromSlash(rel))); err == nil {
+				local, err := filepath.Rel(root, resolved)
+				escapes = err != nil || !filepath.IsLocal(local) && local != "."
+			}
+		}
+		if escapes {
+			return fmt.Errorf("symlink %q points outside the installation: %q", "go/"+rel, linkTarget)
+		}
+	}
+	return nil
+}
+
+// finish checks the symlinks and applies directory permissions, deepest
+// directories first
+func (e *extractor) finish() error {
+	if err := e.checkSymlinks(); err != nil {
+		return err
+	}
+	for i := len(e.dirs) - 1; i >= 0; i-- {
+		d := e.dirs[i]
+		if err := os.Chmod(d.path, d.mode); err != nil {
+			return err
+		}
+		_ = os.Chtimes(d.path, d.modTime, d.modTime)
+	}
+	return nil
+}
+
+func (e *extractor) extractTarGz(archive string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.mkdir(hdr.Name, hdr.FileInfo().Mode(), hdr.ModTime)
+		case tar.TypeReg:
+			err = e.writeFile(hdr.Name, hdr.FileInfo().Mode(), hdr.ModTime, tr)
+		case tar.TypeSymlink:
+			err = e.symlink(hdr.Name, hdr.Linkname)
+		default:
+			err = fmt.Errorf("unsupported entry %q of type %q", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *extractor) extractZip(archive string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = e.mkdir(f.Name, dirPerm(mode), f.Modified)
+		case mode&fs.ModeSymlink != 0:
+			err = e.zipSymlink(f)
+		case mode.IsRegular():
+			err = e.zipFile(f, mode)
+		default:
+			err = fmt.Errorf("unsupported entry %q", f.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) zipFile(f *zip.File, mode fs.FileMode) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// Zip files written on Windows carry no Unix permissions
+	if mode.Perm() == 0 {
+		mode |= 0o644
+	}
+	return e.writeFile(f.Name, mode, f.Modified, rc)
+}
+
+func (e *extractor) zipSymlink(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+	return e.symlink(f.Name, string(target))
+}
+
+func dirPerm(mode fs.FileMode) fs.FileMode {
+	if mode.Perm() == 0 {
+		return mode | 0o755
+	}
+	return mode
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var GoCommand = "go"
+
+type InstalledToolchain struct {
+	GOROOT  string
+	Version string
+	Source  string
+}
+
+// DetectInstalledVersion finds the Go toolchain in goroot, or when goroot is
+// empty in $GOROOT and then through the go command on PATH
+func DetectInstalledVersion(goroot string) (*InstalledToolchain, error) {
+	if goroot != "" {
+		return toolchainFromGOROOT(goroot, "given GOROOT")
+	}
+	if env := os.Getenv("GOROOT"); env != "" {
+		return toolchainFromGOROOT(env, "$GOROOT")
+	}
+
+	goPath, err := exec.LookPath(GoCommand)
+	if err != nil {
+		return nil, errors.New("no Go installation found: GOROOT is not set and go is not on PATH")
+	}
+	out, err := runGo(goPath, "env", "GOROOT")
+	if err == nil && strings.TrimSpace(out) != "" {
+		if tc, err := toolchainFromGOROOT(strings.TrimSpace(out), goPath); err == nil {
+			return tc, nil
+		}
+	}
+
+	out, err = runGo(goPath, "version")
+	if err != nil {
+		return nil, err
+	}
+	version, err := parseGoVersionOutput(out)
+	if err != nil {
+		return nil, err
+	}
+	return &InstalledToolchain{Version: version, Source: goPath}, nil
+}
+
+func toolchainFromGOROOT(goroot, source string) (*InstalledToolchain, error) {
+	version, err := ReadGOROOTVersion(goroot)
+	if err != nil {
+		// Some distributions do not ship the VERSION file, ask the toolchain itself
+		goBin := filepath.Join(goroot, "bin", "go")
+		out, runErr := runGo(goBin, "version")
+		if runErr != nil {
+			return nil, err
+		}
+		if version, err = parseGoVersionOutput(out); err != nil {
+			return nil, err
+		}
+	}
+	return &InstalledToolchain{GOROOT: goroot, Version: version, Source: source}, nil
+}
+
+// ReadGOROOTVersion reads the release from the VERSION file at the root of a Go installation
+func ReadGOROOTVersion(goroot string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Go version from %s: %w", goroot, err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "go") {
+		return "", fmt.Errorf("unexpected VERSION file in %s: %q", goroot, line)
+	}
+	return strings.TrimPrefix(line, "go"), nil
+}
+
+// parseGoVersionOutput extracts 1.22.5 from "go version go1.22.5 linux/amd64"
+func parseGoVersionOutput(out string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == "go" && fields[1] == "version" && strings.HasPrefix(fields[2], "go") {
+			return strings.TrimPrefix(fields[2], "go"), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected go version output: %q", strings.TrimSpace(out))
+}
+
+func runGo(goBin string, args ...string) (string, error) {
+	cmd := exec.Command(goBin, args...)
+	// Report the local toolchain instead of letting go.mod switch to another one
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %v %s", goBin, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -13,6 +13,13 @@ type FileDownloader interface {
 	Download(url, filename string) error
 }
 
+// ChecksumDownloader is implemented by downloaders that compute the SHA-256
+// of a file while writing it, so it does not have to be read back to verify it
+type ChecksumDownloader interface {
+	FileDownloader
+	DownloadWithHash(url, filename string) (string, error)
+}
+
 type FileRemover interface {
 	Remove(filename string) error
 }
@@ -21,3 +28,15 @@ type ChecksumCalculator interface {
 	Calculate(filename string) (string, error)
 	GetOfficialChecksum(filename string) (string, error)
 }
+
+// ReleaseIndex is implemented by checksum calculators that can return the
+// whole release index, which is needed to resolve files other than archives
+type ReleaseIndex interface {
+	Releases() ([]GoRelease, error)
+}
+
+// Verifier checks a downloaded file once its checksum matched. url is where
+// the file was downloaded from, so detached signatures can be found next to it.
+type Verifier interface {
+	Verify(filename, url string) error
+}
@@ -0,0 +1,241 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestChecksumCalculator takes the official checksums from a local
+// manifest instead of go.dev, for machines without internet access. All
+// checksums of a manifest use the same algorithm, SHA-256 or SHA-512.
+type ManifestChecksumCalculator struct {
+	checksums map[string]string
+	algorithm string
+}
+
+// releaseManifest is a manifest read from a copy of the JSON release index,
+// which can also resolve installers and source tarballs
+type releaseManifest struct {
+	*ManifestChecksumCalculator
+	releases []GoRelease
+}
+
+func (m *releaseManifest) Releases() ([]GoRelease, error) {
+	return m.releases, nil
+}
+
+// LoadManifest reads a sha256sum or sha512sum style manifest, in GNU
+// ("<digest>  <file>") or BSD ("SHA256 (<file>) = <digest>") format, or a
+// copy of the JSON release index saved from go.dev
+func LoadManifest(filename string) (ChecksumCalculator, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var releases []GoRelease
+		if err := json.Unmarshal(trimmed, &releases); err != nil {
+			return nil, fmt.Errorf("failed to parse release index %s: %w", filename, err)
+		}
+		m := &ManifestChecksumCalculator{checksums: make(map[string]string)}
+		for _, release := range releases {
+			for _, file := range release.Files {
+				if err := m.add(file.Filename, file.SHA256); err != nil {
+					return nil, fmt.Errorf("%s: %w", filename, err)
+				}
+			}
+		}
+		if len(m.checksums) == 0 {
+			return nil, fmt.Errorf("no checksums found in %s", filename)
+		}
+		return &releaseManifest{ManifestChecksumCalculator: m, releases: releases}, nil
+	}
+
+	m, err := parseChecksumList(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(m.checksums) == 0 {
+		return nil, fmt.Errorf("no checksums found in %s", filename)
+	}
+	return m, nil
+}
+
+func parseChecksumList(r io.Reader) (*ManifestChecksumCalculator, error) {
+	m := &ManifestChecksumCalculator{checksums: make(map[string]string)}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var name, digest string
+		if tag, rest, ok := strings.Cut(line, " ("); ok && (tag == "SHA256" || tag == "SHA512") {
+			name, digest, ok = strings.Cut(rest, ") = ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid checksum line", lineNum)
+			}
+		} else {
+			digest, name, ok = strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid checksum line", lineNum)
+			}
+			// sha256sum marks files read in binary mode with a *
+			name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		}
+		if err := m.add(name, digest); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	return m, scanner.Err()
+}
+
+func (m *ManifestChecksumCalculator) add(name, digest string) error {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	algorithm, err := digestAlgorithm(digest)
+	if err != nil {
+		return err
+	}
+	if m.algorithm != "" && m.algorithm != algorithm {
+		return fmt.Errorf("manifest mixes %s and %s checksums", m.algorithm, algorithm)
+	}
+	m.algorithm = algorithm
+	// Manifests may list files with a directory, such as ./go1.22.5.src.tar.gz
+	m.checksums[path.Base(filepath.ToSlash(strings.TrimSpace(name)))] = digest
+	return nil
+}
+
+func (m *ManifestChecksumCalculator) GetOfficialChecksum(filename string) (string, error) {
+	if digest, ok := m.checksums[filename]; ok {
+		return digest, nil
+	}
+	return "", fmt.Errorf("checksum not found for %s in the checksum manifest", filename)
+}
+
+// Platforms returns the architectures each operating system has a file of
+// the given kind for, read from names such as go1.22.5.linux-amd64.tar.gz
+func (m *ManifestChecksumCalculator) Platforms(version, kind string) map[string][]string {
+	prefix := "go" + strings.TrimPrefix(version, "go") + "."
+	platforms := make(map[string][]string)
+	for name := range m.checksums {
+		platform, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		platform, ext, _ := strings.Cut(platform, ".")
+		fileKind := ""
+		switch ext {
+		case "tar.gz", "zip":
+			fileKind = KindArchive
+		case "msi", "pkg":
+			fileKind = KindInstaller
+		}
+		// The source tarball, go1.22.5.src.tar.gz, has no platform
+		goos, arch, ok := strings.Cut(platform, "-")
+		if fileKind != kind || !ok {
+			continue
+		}
+		platforms[goos] = append(platforms[goos], arch)
+	}
+	for _, arches := range platforms {
+		sort.Strings(arches)
+	}
+	return platforms
+}
+
+// Calculate hashes filename with the algorithm of the manifest
+func (m *ManifestChecksumCalculator) Calculate(filename string) (string, error) {
+	return hashFileWith(filename, m.algorithm)
+}
+
+// digestAlgorithm tells SHA-256 and SHA-512 digests apart by their length
+func digestAlgorithm(digest string) (string, error) {
+	if _, err := hex.DecodeString(digest); err == nil {
+		switch len(digest) {
+		case sha256.Size * 2:
+			return "SHA-256", nil
+		case sha512.Size * 2:
+			return "SHA-512", nil
+		}
+	}
+	return "", fmt.Errorf("invalid checksum %q: must be a SHA-256 or SHA-512 digest", digest)
+}
+
+func isSHA512(digest string) bool {
+	return len(digest) == sha512.Size*2
+}
+
+func hashFileWith(filename, algorithm string) (string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "SHA-512":
+		h = sha512.New()
+	default:
+		h = sha256.New()
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyFile checks filename against an official SHA-256 or SHA-512 digest,
+// picking the algorithm from the digest. It returns the digest of the file.
+func VerifyFile(filename, official string) (string, bool, error) {
+	official = strings.ToLower(official)
+	algorithm, err := digestAlgorithm(official)
+	if err != nil {
+		return "", false, err
+	}
+	sum, err := hashFileWith(filename, algorithm)
+	if err != nil {
+		return "", false, err
+	}
+	return sum, sum == official, nil
+}
+
+// ArchiveCheck is the result of checking an archive on disk against its
+// official checksum
+type ArchiveCheck struct {
+	File     string `json:"file"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CheckArchive looks up the official checksum of filename by its base name
+// and compares it with the file's content
+func CheckArchive(filename string, checksum ChecksumCalculator) ArchiveCheck {
+	result := ArchiveCheck{File: filename}
+	expected, err := checksum.GetOfficialChecksum(filepath.Base(filename))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Expected = strings.ToLower(expected)
+	result.Actual, result.OK, err = VerifyFile(filename, expected)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"runtime"
+	"strings"
+)
+
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+var osAliases = map[string]string{
+	"macos": "darwin",
+	"mac":   "darwin",
+	"osx":   "darwin",
+	"win":   "windows",
+}
+
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"armv8":   "arm64",
+	"i386":    "386",
+	"i686":    "386",
+	"x86":     "386",
+}
+
+// NormalizeOS maps common operating system names to Go's GOOS names
+func NormalizeOS(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := osAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// NormalizeArch maps common architecture names to the names used by the Go
+// downloads. 32-bit ARM is published as armv6l for Linux, which also runs on
+// ARMv7 machines, and as arm for the other systems.
+func NormalizeArch(targetOS, name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := archAliases[name]; ok {
+		return alias
+	}
+	switch name {
+	case "arm", "armv6", "armv6l", "armv7", "armv7l", "armhf":
+		if NormalizeOS(targetOS) == "linux" {
+			return "armv6l"
+		}
+		return "arm"
+	}
+	return name
+}
+
+func NormalizePlatform(targetOS, arch string) Platform {
+	targetOS = NormalizeOS(targetOS)
+	return Platform{OS: targetOS, Arch: NormalizeArch(targetOS, arch)}
+}
+
+// HostPlatform is the platform this program runs on, in download names
+func HostPlatform() Platform {
+	return NormalizePlatform(runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,297 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// ProgressReporter receives the progress of downloads. Several files may be
+// downloaded at the same time, each transfer reports through its own tracker.
+type ProgressReporter interface {
+	// Start is called when a transfer begins, total is -1 when the size is
+	// unknown and offset is the number of bytes resumed from disk
+	Start(name string, total, offset int64) ProgressTracker
+	Retry(name string, attempt, maxRetries int, err error)
+}
+
+// ProgressTracker follows a single transfer. Add may be called from several
+// goroutines when a file is downloaded in segments.
+type ProgressTracker interface {
+	Add(n int64)
+	Finish(err error)
+}
+
+const (
+	ProgressAuto  = "auto"
+	ProgressBar   = "bar"
+	ProgressPlain = "plain"
+	ProgressJSON  = "json"
+	ProgressQuiet = "quiet"
+)
+
+// NewProgressReporter returns the reporter for mode writing to out. The auto
+// mode draws a bar when out is a terminal and logs plain lines otherwise.
+func NewProgressReporter(mode string, out io.Writer) (ProgressReporter, error) {
+	switch mode {
+	case ProgressAuto, "":
+		if f, ok := out.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			return &BarProgress{Output: out}, nil
+		}
+		return &PlainProgress{Output: out}, nil
+	case ProgressBar:
+		return &BarProgress{Output: out}, nil
+	case ProgressPlain:
+		return &PlainProgress{Output: out}, nil
+	case ProgressJSON:
+		return &JSONProgress{Output: out}, nil
+	case ProgressQuiet:
+		return QuietProgress{}, nil
+	}
+	return nil, fmt.Errorf("invalid progress mode %q: must be auto, bar, plain, json or quiet", mode)
+}
+
+// progressOrDefault keeps the interactive bar on stdout for downloaders
+// created without a reporter
+func progressOrDefault(p ProgressReporter) ProgressReporter {
+	if p == nil {
+		return &BarProgress{Output: os.Stdout}
+	}
+	return p
+}
+
+type progressWriter struct {
+	tracker ProgressTracker
+}
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	w.tracker.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// BarProgress draws an interactive progress bar. Bars of concurrent
+// downloads overwrite each other, batch downloads should use another mode.
+type BarProgress struct {
+	Output io.Writer
+}
+
+type barTracker struct {
+	output io.Writer
+	bar    *progressbar.ProgressBar
+}
+
+func (p *BarProgress) Start(name string, total, offset int64) ProgressTracker {
+	if offset > 0 {
+		fmt.Fprintf(p.Output, "Resuming download at %d bytes\n", offset)
+	}
+	bar := progressbar.NewOptions64(
+		total,
+		progressbar.OptionSetWriter(p.Output),
+		progressbar.OptionSetWidth(50),
+		progressbar.OptionSetDescription("Downloading:"),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "=",
+			SaucerHead:    ">",
+			SaucerPadding: " ",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Fprintf(p.Output, "\nDownload Complete!")
+		}),
+	)
+	if offset > 0 {
+		_ = bar.Set64(offset)
+	}
+	return &barTracker{output: p.Output, bar: bar}
+}
+
+func (p *BarProgress) Retry(name string, attempt, maxRetries int, err error) {
+	fmt.Fprintf(p.Output, "\nDownload interrupted (%s), resuming (retry %d of %d)\n", err, attempt, maxRetries)
+}
+
+func (t *barTracker) Add(n int64) {
+	_ = t.bar.Add64(n)
+}
+
+func (t *barTracker) Finish(err error) {
+	if err == nil {
+		fmt.Fprintln(t.output)
+	}
+}
+
+// PlainProgress logs a line for every 10% downloaded, or every 10 MiB when the
+// size is unknown, which suits CI logs and redirected output
+type PlainProgress struct {
+	Output io.Writer
+
+	mu sync.Mutex
+}
+
+type plainTracker struct {
+	p       *PlainProgress
+	name    string
+	total   int64
+	current int64
+	next    int64
+}
+
+const plainUnknownStep = 10 << 20
+
+func (p *PlainProgress) printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.Output, format, args...)
+}
+
+func (p *PlainProgress) Start(name string, total, offset int64) ProgressTracker {
+	t := &plainTracker{p: p, name: name, total: total, current: offset}
+	t.next = t.step()
+	if offset > 0 {
+		p.printf("Resuming %s at %d bytes\n", name, offset)
+	} else {
+		p.printf("Downloading %s (%s)\n", name, formatSize(total))
+	}
+	return t
+}
+
+func (p *PlainProgress) Retry(name string, attempt, maxRetries int, err error) {
+	p.printf("Download of %s interrupted (%s), retry %d of %d\n", name, err, attempt, maxRetries)
+}
+
+func (t *plainTracker) step() int64 {
+	if t.total <= 0 {
+		return (t.current/plainUnknownStep + 1) * plainUnknownStep
+	}
+	return (t.current*10/t.total + 1) * t.total / 10
+}
+
+func (t *plainTracker) Add(n int64) {
+	t.p.mu.Lock()
+	defer t.p.mu.Unlock()
+	t.current += n
+	if t.current < t.next || (t.total > 0 && t.current >= t.total) {
+		return
+	}
+	if t.total > 0 {
+		fmt.Fprintf(t.p.Output, "Downloading %s: %d%% (%d of %d bytes)\n", t.name, t.current*100/t.total, t.current, t.total)
+	} else {
+		fmt.Fprintf(t.p.Output, "Downloading %s: %d bytes\n", t.name, t.current)
+	}
+	t.next = t.step()
+}
+
+func (t *plainTracker) Finish(err error) {
+	t.p.mu.Lock()
+	defer t.p.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(t.p.Output, "Download of %s failed after %d bytes\n", t.name, t.current)
+		return
+	}
+	fmt.Fprintf(t.p.Output, "Downloaded %s (%d bytes)\n", t.name, t.current)
+}
+
+// JSONProgress writes newline-delimited JSON events, progress events are
+// emitted at most once per percent or once a second when the size is unknown
+type JSONProgress struct {
+	Output io.Writer
+
+	mu sync.Mutex
+}
+
+type jsonTracker struct {
+	p        *JSONProgress
+	name     string
+	total    int64
+	current  int64
+	reported int64
+	lastTime time.Time
+}
+
+type progressEvent struct {
+	Event   string `json:"event"`
+	File    string `json:"file"`
+	Total   int64  `json:"total,omitempty"`
+	Bytes   int64  `json:"bytes"`
+	Attempt int    `json:"attempt,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// emit must be called with p.mu held
+func (p *JSONProgress) emit(event progressEvent) {
+	if data, err := json.Marshal(event); err == nil {
+		fmt.Fprintf(p.Output, "%s\n", data)
+	}
+}
+
+func (t *jsonTracker) event(name string) progressEvent {
+	event := progressEvent{Event: name, File: t.name, Bytes: t.current}
+	if t.total > 0 {
+		event.Total = t.total
+	}
+	return event
+}
+
+func (p *JSONProgress) Start(name string, total, offset int64) ProgressTracker {
+	t := &jsonTracker{p: p, name: name, total: total, current: offset, reported: offset, lastTime: time.Now()}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(t.event("start"))
+	return t
+}
+
+func (p *JSONProgress) Retry(name string, attempt, maxRetries int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(progressEvent{Event: "retry", File: name, Attempt: attempt, Error: err.Error()})
+}
+
+func (t *jsonTracker) Add(n int64) {
+	t.p.mu.Lock()
+	defer t.p.mu.Unlock()
+	t.current += n
+	if t.total > 0 {
+		if (t.current-t.reported)*100 < t.total {
+			return
+		}
+	} else if time.Since(t.lastTime) < time.Second {
+		return
+	}
+	t.reported, t.lastTime = t.current, time.Now()
+	t.p.emit(t.event("progress"))
+}
+
+func (t *jsonTracker) Finish(err error) {
+	t.p.mu.Lock()
+	defer t.p.mu.Unlock()
+	if err != nil {
+		event := t.event("error")
+		event.Error = err.Error()
+		t.p.emit(event)
+		return
+	}
+	t.p.emit(t.event("done"))
+}
+
+// QuietProgress discards all progress
+type QuietProgress struct{}
+
+func (QuietProgress) Start(string, int64, int64) ProgressTracker { return QuietProgress{} }
+func (QuietProgress) Retry(string, int, int, error)              {}
+func (QuietProgress) Add(int64)                                  {}
+func (QuietProgress) Finish(error)                               {}
+
+func formatSize(n int64) string {
+	if n < 0 {
+		return "unknown size"
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter caps the combined throughput of every reader it wraps, so
+// segments and concurrent downloads share one bandwidth budget
+type RateLimiter struct {
+	bytesPerSecond int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimiter returns a limiter for the given number of bytes per second
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	return &RateLimiter{bytesPerSecond: bytesPerSecond}
+}
+
+// wait blocks until n more bytes fit in the budget. Each call reserves its
+// share of time after the reservations before it.
+func (l *RateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.bytesPerSecond) * float64(time.Second)))
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// Reader wraps r so reads from it count against the limit. A nil limiter
+// returns r unchanged.
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil || l.bytesPerSecond <= 0 {
+		return r
+	}
+	return &rateLimitedReader{r: r, limiter: l}
+}
+
+type rateLimitedReader struct {
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Small reads keep the transfer smooth instead of bursting a large buffer
+	if chunk := int(r.limiter.bytesPerSecond / 10); chunk > 0 && len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.limiter.wait(n)
+	}
+	return n, err
+}
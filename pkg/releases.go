@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of files in the release index
+const (
+	KindArchive   = "archive"
+	KindInstaller = "installer"
+	KindSource    = "source"
+)
+
+// DownloadBaseURL is where the files listed in the release index are served
+var DownloadBaseURL = "https://dl.google.com/go/"
+
+// ParseFileKind validates a -kind value, an empty string means archive
+func ParseFileKind(kind string) (string, error) {
+	switch kind {
+	case "":
+		return KindArchive, nil
+	case KindArchive, KindInstaller, KindSource:
+		return kind, nil
+	}
+	return "", fmt.Errorf("invalid file kind %q: must be archive, installer or source", kind)
+}
+
+// FindReleaseFile returns the file of the given kind for a release. The
+// platform is ignored for the source tarball.
+func FindReleaseFile(releases []GoRelease, version, targetOS, arch, kind string) (ReleaseFile, error) {
+	version = "go" + strings.TrimPrefix(version, "go")
+	for _, release := range releases {
+		if release.Version != version {
+			continue
+		}
+		for _, file := range release.Files {
+			if file.Kind != kind {
+				continue
+			}
+			if kind == KindSource || (file.OS == targetOS && file.Arch == arch) {
+				return file, nil
+			}
+		}
+		if kind == KindSource {
+			return ReleaseFile{}, fmt.Errorf("no source tarball listed for %s", version)
+		}
+		return ReleaseFile{}, fmt.Errorf("no %s listed for %s on %s/%s", kind, version, targetOS, arch)
+	}
+	return ReleaseFile{}, fmt.Errorf("release %s not found in the release index", version)
+}
+
+func resolveReleaseFile(config DownloadConfig, version, kind string) (ReleaseFile, error) {
+	index, ok := config.Checksum.(ReleaseIndex)
+	if !ok {
+		return ReleaseFile{}, fmt.Errorf("downloading the %s requires the release index", kind)
+	}
+	releases, err := index.Releases()
+	if err != nil {
+		return ReleaseFile{}, err
+	}
+	return FindReleaseFile(releases, version, config.TargetOS, config.Arch, kind)
+}
+
+// ReleasePlatforms returns the architectures published for each operating
+// system in a release, counting only files of the given kind
+func ReleasePlatforms(releases []GoRelease, version, kind string) (map[string][]string, error) {
+	version = "go" + strings.TrimPrefix(version, "go")
+	for _, release := range releases {
+		if release.Version != version {
+			continue
+		}
+		platforms := make(map[string][]string)
+		for _, file := range release.Files {
+			if file.Kind == kind && file.OS != "" && file.Arch != "" {
+				platforms[file.OS] = append(platforms[file.OS], file.Arch)
+			}
+		}
+		for _, arches := range platforms {
+			sort.Strings(arches)
+		}
+		return platforms, nil
+	}
+	return nil, fmt.Errorf("release %s not found in the release index", version)
+}
+
+// platformLister is implemented by checksum sources that know the platforms
+// of a release without a release index, such as a checksum manifest
+type platformLister interface {
+	Platforms(version, kind string) map[string][]string
+}
+
+// platformsFor reads the platforms of a release from the release index, or
+// from the files a checksum manifest lists, and falls back to the built-in
+// list when neither can be used
+func platformsFor(config DownloadConfig, version, kind string) map[string][]string {
+	index, ok := config.Checksum.(ReleaseIndex)
+	if !ok {
+		if lister, ok := config.Checksum.(platformLister); ok {
+			if platforms := lister.Platforms(version, kind); len(platforms) > 0 {
+				return platforms
+			}
+		}
+		return validPlatforms
+	}
+	releases, err := index.Releases()
+	if err == nil {
+		var platforms map[string][]string
+		if platforms, err = ReleasePlatforms(releases, version, kind); err == nil && len(platforms) > 0 {
+			return platforms
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(config.Output, "Warning: could not read the platforms of Go %s from the release index (%s), using the built-in list\n", version, err)
+	}
+	return validPlatforms
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// suggest returns the candidates closest to value, to help with typos
+func suggest(value string, candidates []string) []string {
+	best, bestDistance := []string(nil), 3
+	for _, c := range candidates {
+		d := editDistance(value, c)
+		switch {
+		case d < bestDistance:
+			best, bestDistance = []string{c}, d
+		case d == bestDistance:
+			best = append(best, c)
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func unsupportedOSError(targetOS, version string, platforms map[string][]string) error {
+	msg := fmt.Sprintf("unsupported operating system: %s", targetOS)
+	if hint := suggest(targetOS, sortedKeys(platforms)); len(hint) > 0 {
+		return fmt.Errorf("%s (did you mean %s?)", msg, strings.Join(hint, " or "))
+	}
+	return fmt.Errorf("%s (Go %s is available for %s)", msg, version, strings.Join(sortedKeys(platforms), ", "))
+}
+
+func unsupportedArchError(arch, targetOS, version string, platforms map[string][]string) error {
+	msg := fmt.Sprintf("unsupported architecture %s for OS %s", arch, targetOS)
+	var others []string
+	for _, os := range sortedKeys(platforms) {
+		if os != targetOS && isValidArchitecture(arch, platforms[os]) {
+			others = append(others, os+"/"+arch)
+		}
+	}
+	hint := fmt.Sprintf("Go %s is available for %s on %s", version, targetOS, strings.Join(platforms[targetOS], ", "))
+	if len(others) > 0 {
+		hint += ", and for " + strings.Join(others, ", ")
+	}
+	return fmt.Errorf("%s (%s)", msg, hint)
+}
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resumeState is stored next to a partial download so a later attempt can
+// ask the server for the remaining bytes of the exact same file
+type resumeState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Size         int64  `json:"size"`
+	// Remaining lists the byte ranges a segmented download still has to
+	// fetch. Its file is allocated at full size up front, so the size on disk
+	// does not tell how much has been written.
+	Remaining []segment `json:"remaining,omitempty"`
+}
+
+func resumeStateFilename(filename string) string {
+	return filename + ".meta"
+}
+
+func (s resumeState) validator() string {
+	if s.ETag != "" {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// loadResumeState returns the saved state and the offset to resume from,
+// the offset is 0 when the partial file cannot be resumed safely
+func loadResumeState(url, filename string) (resumeState, int64) {
+	var state resumeState
+	data, err := os.ReadFile(resumeStateFilename(filename))
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return resumeState{}, 0
+	}
+	info, err := os.Stat(filename)
+	if err != nil || state.URL != url || state.validator() == "" || len(state.Remaining) > 0 {
+		return resumeState{}, 0
+	}
+	if state.Size > 0 && info.Size() > state.Size {
+		return resumeState{}, 0
+	}
+	return state, info.Size()
+}
+
+func saveResumeState(filename string, state resumeState) {
+	// Without a validator a changed file on the server could not be detected
+	if state.validator() == "" {
+		clearResumeState(filename)
+		return
+	}
+	if data, err := json.Marshal(state); err == nil {
+		_ = os.WriteFile(resumeStateFilename(filename), data, 0o644)
+	}
+}
+
+func clearResumeState(filename string) {
+	_ = os.Remove(resumeStateFilename(filename))
+}
+
+// parseContentRange parses "bytes 100-199/200" into its start and total size
+func parseContentRange(header string) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	startStr, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total := int64(-1)
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
+
+// hashPrefix feeds the first n bytes of a partial file into h
+func hashPrefix(h hash.Hash, filename string, n int64) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(h, f, n)
+	return err
+}
+
+func hashFile(filename string) (string, error) {
+	h := sha256.New()
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+	if err := hashPrefix(h, filename, info.Size()); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
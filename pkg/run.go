@@ -46,12 +46,83 @@ func GetDownloadPath(input io.Reader, output io.Writer) string {
 	}
 }
 
+type RunConfig struct {
+	VersionFiles   []string
+	CurrentVersion string
+	Installed      bool
+	GOROOT         string
+	Combine        CombineMode
+	TargetOS       string
+	TargetArch     string
+	// Platforms downloads several platforms at once instead of TargetOS and
+	// TargetArch, with at most Workers downloads running at the same time
+	Platforms []Platform
+	Workers   int
+	Input     io.Reader
+	Output    io.Writer
+}
+
 func Run(service VersionChecker, versionFile, currentVersion, targetOS, targetArch string, input io.Reader, output io.Writer) error {
-	if versionFile == "" && currentVersion == "" {
-		return fmt.Errorf("error: Either -file (-f) or -version (-v) must be specified")
+	var versionFiles []string
+	if versionFile != "" {
+		versionFiles = []string{versionFile}
+	}
+	return RunWithConfig(service, RunConfig{
+		VersionFiles:   versionFiles,
+		CurrentVersion: currentVersion,
+		TargetOS:       targetOS,
+		TargetArch:     targetArch,
+		Input:          input,
+		Output:         output,
+	})
+}
+
+func resolveCurrentVersion(service VersionChecker, config RunConfig) (string, error) {
+	if config.Installed || config.GOROOT != "" {
+		toolchain, err := DetectInstalledVersion(config.GOROOT)
+		if err != nil {
+			return "", err
+		}
+		if toolchain.GOROOT != "" {
+			fmt.Fprintf(config.Output, "Installed Go found at %s (%s)\n", toolchain.GOROOT, toolchain.Source)
+		} else {
+			fmt.Fprintf(config.Output, "Installed Go found via %s\n", toolchain.Source)
+		}
+		return toolchain.Version, nil
+	}
+	if len(config.VersionFiles) == 0 {
+		return service.GetCurrentVersion("", config.CurrentVersion)
+	}
+
+	files, err := ExpandVersionFiles(config.VersionFiles)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 1 {
+		return service.GetCurrentVersion(files[0], config.CurrentVersion)
+	}
+
+	versions := make([]SourceVersion, 0, len(files))
+	for _, file := range files {
+		version, err := service.GetCurrentVersion(file, "")
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", file, err)
+		}
+		fmt.Fprintf(config.Output, "%s: %s\n", file, version)
+		versions = append(versions, SourceVersion{Source: file, Version: version})
+	}
+	return CombineVersions(versions, config.Combine)
+}
+
+func RunWithConfig(service VersionChecker, config RunConfig) error {
+	// The prompts share one reader, a second reader would miss the answers the
+	// first one has already buffered
+	input, output := bufio.NewReader(config.Input), config.Output
+	if len(config.VersionFiles) == 0 && config.CurrentVersion == "" && !config.Installed && config.GOROOT == "" {
+		return fmt.Errorf("error: Either -file (-f), -version (-v) or -installed must be specified")
 	}
 
-	cv, err := service.GetCurrentVersion(versionFile, currentVersion)
+	cv, err := resolveCurrentVersion(service, config)
 	if err != nil {
 		return fmt.Errorf("error getting current version: %v", err)
 	}
@@ -71,7 +142,10 @@ func Run(service VersionChecker, versionFile, currentVersion, targetOS, targetAr
 				fmt.Fprintln(output, "Download cancelled by user")
 				return nil
 			}
-			err := service.DownloadGo(latestVersion, targetOS, targetArch, downloadPath, input, output)
+			if len(config.Platforms) > 0 {
+				return downloadPlatforms(service, config, latestVersion, downloadPath)
+			}
+			err := service.DownloadGo(latestVersion, config.TargetOS, config.TargetArch, downloadPath, input, output)
 			if err != nil {
 				return fmt.Errorf("error downloading Go: %v", err)
 			}
@@ -84,3 +158,15 @@ func Run(service VersionChecker, versionFile, currentVersion, targetOS, targetAr
 	}
 	return nil
 }
+
+func downloadPlatforms(service VersionChecker, config RunConfig, version, downloadPath string) error {
+	fmt.Fprintf(config.Output, "Downloading %s for %d platforms\n", version, len(config.Platforms))
+	results := RunBatch(config.Platforms, config.Workers, config.Output, func(p Platform, output io.Writer) error {
+		return service.DownloadGo(version, p.OS, p.Arch, downloadPath, strings.NewReader(""), output)
+	})
+	if err := WriteBatchSummary(config.Output, results); err != nil {
+		return fmt.Errorf("error downloading Go: %v", err)
+	}
+	fmt.Fprintf(config.Output, "%s has been downloaded to %s\n", version, downloadPath)
+	return nil
+}
@@ -0,0 +1,193 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// FileSource lists and reads files from a tree, e.g. the working tree or a git revision
+type FileSource interface {
+	ListFiles() ([]string, error)
+	ReadFile(name string) ([]byte, error)
+}
+
+type VersionPin struct {
+	Path       string     `json:"path"`
+	Version    string     `json:"version,omitempty"`
+	Rule       string     `json:"rule,omitempty"`
+	Confidence Confidence `json:"-"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// PinChange is a pin that differs between two scans. A file whose version
+// could not be read on one side carries the error instead of a version, so it
+// is not mistaken for an added or removed pin.
+type PinChange struct {
+	Path       string `json:"path"`
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version,omitempty"`
+	OldError   string `json:"old_error,omitempty"`
+	NewError   string `json:"new_error,omitempty"`
+}
+
+var skippedDirs = map[string]bool{".git": true, "vendor": true, "node_modules": true}
+
+type WorkTreeSource struct {
+	Root string
+}
+
+func (w *WorkTreeSource) ListFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(w.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != w.Root && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(w.Root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+func (w *WorkTreeSource) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(w.Root, filepath.FromSlash(name)))
+}
+
+// IsVersionPinFile reports whether a file is one that commonly pins a Go version
+func IsVersionPinFile(name string) bool {
+	base := path.Base(filepath.ToSlash(name))
+	switch base {
+	case "go.mod", "go.work", ".go-version", ".tool-versions", "Dockerfile", "Containerfile":
+		return true
+	}
+	return strings.HasPrefix(base, "Dockerfile.") || strings.HasSuffix(base, ".Dockerfile")
+}
+
+func ScanVersionPins(src FileSource, opts ExtractOptions) ([]VersionPin, error) {
+	files, err := src.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var pins []VersionPin
+	for _, name := range files {
+		if !IsVersionPinFile(name) {
+			continue
+		}
+		content, err := src.ReadFile(name)
+		if err != nil {
+			pins = append(pins, VersionPin{Path: name, Error: err.Error()})
+			continue
+		}
+		if pin, ok := pinFromContent(name, content, opts); ok {
+			pins = append(pins, pin)
+		}
+	}
+	return pins, nil
+}
+
+func pinFromContent(name string, content []byte, opts ExtractOptions) (VersionPin, bool) {
+	// A .go-version file holds nothing but the version, so a bare number is reliable there
+	if path.Base(name) == ".go-version" {
+		version := strings.TrimPrefix(strings.TrimSpace(string(content)), "go")
+		if version != "" {
+			return VersionPin{Path: name, Version: version, Rule: ".go-version file", Confidence: ConfidenceHigh}, true
+		}
+	}
+
+	match, err := ExtractGoVersionMatch(string(content), opts)
+	if err != nil {
+		var ambiguous *AmbiguousVersionError
+		if errors.As(err, &ambiguous) {
+			return VersionPin{Path: name, Error: err.Error()}, true
+		}
+		return VersionPin{}, false
+	}
+	return VersionPin{Path: name, Version: match.Version, Rule: match.Rule, Confidence: match.Confidence}, true
+}
+
+func DiffVersionPins(before, after []VersionPin) []PinChange {
+	oldPins := pinsByPath(before)
+	newPins := pinsByPath(after)
+
+	var changes []PinChange
+	for p, oldPin := range oldPins {
+		newPin, ok := newPins[p]
+		if !ok {
+			changes = append(changes, PinChange{Path: p, OldVersion: oldPin.Version, OldError: oldPin.Error})
+		} else if newPin.Version != oldPin.Version || newPin.Error != oldPin.Error {
+			changes = append(changes, PinChange{Path: p, OldVersion: oldPin.Version, NewVersion: newPin.Version, OldError: oldPin.Error, NewError: newPin.Error})
+		}
+	}
+	for p, newPin := range newPins {
+		if _, ok := oldPins[p]; !ok {
+			changes = append(changes, PinChange{Path: p, NewVersion: newPin.Version, NewError: newPin.Error})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func pinsByPath(pins []VersionPin) map[string]VersionPin {
+	byPath := make(map[string]VersionPin, len(pins))
+	for _, pin := range pins {
+		byPath[pin.Path] = pin
+	}
+	return byPath
+}
+
+func WriteScanReport(w io.Writer, pins []VersionPin) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tVERSION\tRULE")
+	for _, pin := range pins {
+		if pin.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\terror: %s\n", pin.Path, pin.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s (%s confidence)\n", pin.Path, pin.Version, pin.Rule, pin.Confidence)
+	}
+	return tw.Flush()
+}
+
+func WriteDiffReport(w io.Writer, changes []PinChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No Go version pins changed")
+		return
+	}
+	for _, c := range changes {
+		oldPin, newPin := pinText(c.OldVersion, c.OldError), pinText(c.NewVersion, c.NewError)
+		switch {
+		case oldPin == "":
+			fmt.Fprintf(w, "%s: added %s\n", c.Path, newPin)
+		case newPin == "":
+			fmt.Fprintf(w, "%s: removed (was %s)\n", c.Path, oldPin)
+		default:
+			fmt.Fprintf(w, "%s: %s -> %s\n", c.Path, oldPin, newPin)
+		}
+	}
+}
+
+func pinText(version, err string) string {
+	if err != "" {
+		return "error (" + err + ")"
+	}
+	return version
+}
@@ -0,0 +1,382 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CurrentLink is the name of the symlink to the active toolchain
+const CurrentLink = "current"
+
+// HistoryFile records every toolchain installed and activated in the root,
+// one JSON object per line
+const HistoryFile = "history.jsonl"
+
+// SDKManager keeps Go toolchains side by side under Root, each in a
+// go<version> directory, with the current symlink pointing at the active one
+type SDKManager struct {
+	Root string
+}
+
+// SDK is a toolchain installed by an SDKManager
+type SDK struct {
+	Version string `json:"version"`
+	GOROOT  string `json:"goroot"`
+	Current bool   `json:"current"`
+}
+
+// HistoryEntry is one install or switch recorded in the history file. Action
+// is install, use or rollback, Previous is the version that was current
+// before a switch.
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Version  string    `json:"version"`
+	Previous string    `json:"previous,omitempty"`
+}
+
+// DefaultSDKRoot is ~/.automatedgo/sdk
+func DefaultSDKRoot() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the home directory: %w", err)
+	}
+	return filepath.Join(home, ".automatedgo", "sdk"), nil
+}
+
+// GOROOT returns the directory version is installed in, whether or not it is
+// installed
+func (m *SDKManager) GOROOT(version string) string {
+	return filepath.Join(m.Root, "go"+strings.TrimPrefix(version, "go"))
+}
+
+// IsInstalled reports whether version is installed
+func (m *SDKManager) IsInstalled(version string) bool {
+	installed, err := ReadGOROOTVersion(m.GOROOT(version))
+	return err == nil && installed == strings.TrimPrefix(version, "go")
+}
+
+// Installed lists the installed toolchains, newest first
+func (m *SDKManager) Installed() ([]SDK, error) {
+	entries, err := os.ReadDir(m.Root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SDK directory: %w", err)
+	}
+
+	current, _ := m.Current()
+	var sdks []SDK
+	for _, e := range entries {
+		// Staging directories of an install in progress start with a dot
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "go") {
+			continue
+		}
+		goroot := filepath.Join(m.Root, e.Name())
+		version, err := ReadGOROOTVersion(goroot)
+		if err != nil || "go"+version != e.Name() {
+			continue
+		}
+		sdks = append(sdks, SDK{Version: version, GOROOT: goroot, Current: version == current})
+	}
+	sort.Slice(sdks, func(i, j int) bool {
+		return IsNewer(sdks[i].Version, sdks[j].Version)
+	})
+	return sdks, nil
+}
+
+// Current returns the version the current symlink points at, or "" when no
+// version is active
+func (m *SDKManager) Current() (string, error) {
+	target, err := os.Readlink(filepath.Join(m.Root, CurrentLink))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the current toolchain: %w", err)
+	}
+	return strings.TrimPrefix(filepath.Base(target), "go"), nil
+}
+
+// Use points the current symlink at an installed version. The link is
+// replaced with a rename, so it always points at a complete toolchain.
+func (m *SDKManager) Use(version string) error {
+	return m.activate(strings.TrimPrefix(version, "go"), "use")
+}
+
+func (m *SDKManager) activate(version, action string) error {
+	if !m.IsInstalled(version) {
+		return fmt.Errorf("Go %s is not installed in %s", version, m.Root)
+	}
+	previous, err := m.Current()
+	if err != nil {
+		return err
+	}
+
+	link := filepath.Join(m.Root, CurrentLink)
+	tmp := link + ".new"
+	_ = os.Remove(tmp)
+	// A relative target keeps the link valid when the root is moved
+	if err := os.Symlink("go"+version, tmp); err != nil {
+		return fmt.Errorf("failed to switch to Go %s: %w", version, err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to switch to Go %s: %w", version, err)
+	}
+	return m.record(HistoryEntry{Action: action, Version: version, Previous: previous})
+}
+
+// InstallFunc installs a version into goroot, such as VersionService.Install
+type InstallFunc func(version, goroot string) error
+
+// Install installs version side by side with install and records it in the
+// history
+func (m *SDKManager) Install(version string, install InstallFunc) error {
+	version = strings.TrimPrefix(version, "go")
+	if err := install(version, m.GOROOT(version)); err != nil {
+		return err
+	}
+	return m.record(HistoryEntry{Action: "install", Version: version})
+}
+
+// Rollback switches back to the version that was current before the last
+// switch to the current version, installing it again with install when it
+// has been removed since. It returns the versions switched from and to.
+func (m *SDKManager) Rollback(install InstallFunc) (string, string, error) {
+	current, err := m.Current()
+	if err != nil {
+		return "", "", err
+	}
+	history, err := m.History()
+	if err != nil {
+		return "", "", err
+	}
+
+	previous := ""
+	for i := len(history) - 1; i >= 0; i-- {
+		e := history[i]
+		if e.Action != "install" && e.Version == current && e.Previous != "" && e.Previous != current {
+			previous = e.Previous
+			break
+		}
+	}
+	if current == "" || previous == "" {
+		return "", "", errors.New("no previous Go version to roll back to")
+	}
+
+	if !m.IsInstalled(previous) {
+		if err := m.Install(previous, install); err != nil {
+			return "", "", fmt.Errorf("failed to reinstall Go %s: %w", previous, err)
+		}
+	}
+	if err := m.activate(previous, "rollback"); err != nil {
+		return "", "", err
+	}
+	return current, previous, nil
+}
+
+func (m *SDKManager) record(e HistoryEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Root, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(m.Root, HistoryFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to record toolchain history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to record toolchain history: %w", err)
+	}
+	return f.Close()
+}
+
+// History returns the recorded installs and switches, oldest first. Lines
+// that cannot be parsed are skipped.
+func (m *SDKManager) History() ([]HistoryEntry, error) {
+	f, err := os.Open(filepath.Join(m.Root, HistoryFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read toolchain history: %w", err)
+	}
+	defer f.Close()
+
+	var history []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Version != "" {
+			history = append(history, e)
+		}
+	}
+	return history, scanner.Err()
+}
+
+// WriteTransition reports a switch between two toolchains. use and rollback
+// print it the same way.
+func WriteTransition(w io.Writer, from, to string) {
+	switch {
+	case from == "":
+		fmt.Fprintf(w, "Go %s is now the current version\n", to)
+	case from == to:
+		fmt.Fprintf(w, "Go %s is already the current version\n", to)
+	case IsNewer(to, from):
+		fmt.Fprintf(w, "Go %s -> %s (upgrade)\n", from, to)
+	default:
+		fmt.Fprintf(w, "Go %s -> %s (downgrade)\n", from, to)
+	}
+}
+
+// Which returns the GOROOT of version, or of the current toolchain when
+// version is empty
+func (m *SDKManager) Which(version string) (string, error) {
+	if version == "" {
+		current, err := m.Current()
+		if err != nil {
+			return "", err
+		}
+		if current == "" {
+			return "", fmt.Errorf("no Go version is in use in %s", m.Root)
+		}
+		version = current
+	}
+	if !m.IsInstalled(version) {
+		return "", fmt.Errorf("Go %s is not installed in %s", strings.TrimPrefix(version, "go"), m.Root)
+	}
+	return m.GOROOT(version), nil
+}
+
+// Uninstall removes an installed version. The current toolchain cannot be
+// removed. The directory is moved aside before it is deleted, so a removal
+// that fails half way does not leave a broken toolchain behind.
+func (m *SDKManager) Uninstall(version string) error {
+	version = strings.TrimPrefix(version, "go")
+	if !m.IsInstalled(version) {
+		return fmt.Errorf("Go %s is not installed in %s", version, m.Root)
+	}
+	if current, _ := m.Current(); current == version {
+		return fmt.Errorf("Go %s is the current toolchain, switch to another version with use first", version)
+	}
+
+	removing, err := os.MkdirTemp(m.Root, ".go"+version+".remove-*")
+	if err != nil {
+		return fmt.Errorf("failed to remove Go %s: %w", version, err)
+	}
+	trash := filepath.Join(removing, "go")
+	if err := os.Rename(m.GOROOT(version), trash); err != nil {
+		os.Remove(removing)
+		return fmt.Errorf("failed to remove Go %s: %w", version, err)
+	}
+	if err := removeTree(removing); err != nil {
+		return fmt.Errorf("removed Go %s but failed to delete %s: %w", version, removing, err)
+	}
+	return nil
+}
+
+// removeTree deletes dir, including read-only directories inside it
+func removeTree(dir string) error {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(path, 0o755)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
+// PruneCandidates returns the installed versions a prune removes: all but
+// the keep newest ones, never the current toolchain, and never a version
+// matched by one of pins. A pin such as 1.22 matches every 1.22.x release.
+func (m *SDKManager) PruneCandidates(keep int, pins []string) ([]SDK, error) {
+	sdks, err := m.Installed()
+	if err != nil {
+		return nil, err
+	}
+	var remove []SDK
+	for i, sdk := range sdks {
+		if i < keep || sdk.Current || isPinned(sdk.Version, pins) {
+			continue
+		}
+		remove = append(remove, sdk)
+	}
+	return remove, nil
+}
+
+func isPinned(version string, pins []string) bool {
+	for _, pin := range pins {
+		pin = strings.TrimPrefix(pin, "go")
+		if pin == version || strings.HasPrefix(version, pin+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// PinnedVersions scans the working trees of repos for Go version pins and
+// returns every version they reference. All high and medium confidence
+// matches of a pin file count, such as both the go and toolchain lines of a
+// go.mod, so no referenced version is removed by mistake.
+func PinnedVersions(repos []string) ([]string, error) {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, repo := range repos {
+		src := &WorkTreeSource{Root: repo}
+		files, err := src.ListFiles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s for version pins: %w", repo, err)
+		}
+		sort.Strings(files)
+		for _, name := range files {
+			if !IsVersionPinFile(name) {
+				continue
+			}
+			content, err := src.ReadFile(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(repo, name), err)
+			}
+			for _, version := range pinnedInFile(name, content) {
+				if !seen[version] {
+					seen[version] = true
+					versions = append(versions, version)
+				}
+			}
+		}
+	}
+	return versions, nil
+}
+
+func pinnedInFile(name string, content []byte) []string {
+	if path.Base(name) == ".go-version" {
+		if pin, ok := pinFromContent(name, content, ExtractOptions{Strict: true}); ok && pin.Version != "" {
+			return []string{pin.Version}
+		}
+		return nil
+	}
+	var versions []string
+	for _, c := range ExtractGoVersionCandidates(string(content)) {
+		if c.Confidence >= ConfidenceMedium {
+			versions = append(versions, strings.TrimPrefix(c.Version, "go"))
+		}
+	}
+	return versions
+}
@@ -0,0 +1,221 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Files smaller than this are not worth splitting into several requests
+const minSegmentSize = 1 << 20
+
+// SegmentedDownloader fetches byte ranges of a file over several concurrent
+// connections and writes them into place. Servers that do not support range
+// requests, and small files, are downloaded with a single DefaultDownloader.
+type SegmentedDownloader struct {
+	Connections int
+	MaxRetries  int
+	Progress    ProgressReporter
+	RateLimit   *RateLimiter
+}
+
+// segment is an inclusive byte range
+type segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+func (d *SegmentedDownloader) Download(url, filename string) error {
+	_, err := d.DownloadWithHash(url, filename)
+	return err
+}
+
+// DownloadWithHash returns the SHA-256 of the downloaded file. Single
+// connection downloads hash the stream, segments arrive out of order so the
+// assembled file is hashed once all of them have been written.
+func (d *SegmentedDownloader) DownloadWithHash(url, filename string) (string, error) {
+	single := &DefaultDownloader{MaxRetries: d.MaxRetries, Progress: d.Progress, RateLimit: d.RateLimit}
+	if d.Connections <= 1 {
+		return single.DownloadWithHash(url, filename)
+	}
+
+	resp, err := http.Head(url)
+	if err != nil {
+		return "", fmt.Errorf("error downloading: %w", err)
+	}
+	resp.Body.Close()
+	size := resp.ContentLength
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || size < minSegmentSize {
+		return single.DownloadWithHash(url, filename)
+	}
+	state := resumeState{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), Size: size}
+
+	segments := loadSegments(filename, state)
+	var out *os.File
+	if segments != nil {
+		out, err = os.OpenFile(filename, os.O_WRONLY, 0o644)
+	} else {
+		segments = splitSegments(size, d.Connections)
+		if out, err = os.Create(filename); err == nil {
+			err = out.Truncate(size)
+		}
+	}
+	if out != nil {
+		defer out.Close()
+	}
+	if err != nil {
+		return "", fmt.Errorf("error creating file: %w", err)
+	}
+	state.Remaining = segments
+	saveResumeState(filename, state)
+
+	done := size
+	for _, seg := range segments {
+		done -= seg.End - seg.Start + 1
+	}
+	name := filepath.Base(filename)
+	reporter := progressOrDefault(d.Progress)
+	progress := reporter.Start(name, size, done)
+	errs := make([]error, len(segments))
+	remaining := make([]segment, len(segments))
+	var wg sync.WaitGroup
+	for i, seg := range segments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remaining[i], errs[i] = d.downloadSegment(url, state.validator(), seg, out, progress, func(attempt int, err error) {
+				reporter.Retry(name, attempt, d.MaxRetries, err)
+			})
+		}()
+	}
+	wg.Wait()
+
+	err = errors.Join(errs...)
+	progress.Finish(err)
+	if err != nil {
+		out.Close()
+		// The ranges already written are kept for the next attempt, unless the
+		// file changed on the server or a change could not be detected
+		state.Remaining = nil
+		for i := range errs {
+			if errs[i] != nil {
+				state.Remaining = append(state.Remaining, remaining[i])
+			}
+		}
+		var statusErr *statusError
+		if errors.As(err, &statusErr) || state.validator() == "" {
+			clearResumeState(filename)
+			os.Remove(filename)
+			return "", err
+		}
+		saveResumeState(filename, state)
+		return "", err
+	}
+	clearResumeState(filename)
+	return hashFile(filename)
+}
+
+// loadSegments returns the ranges an interrupted segmented download of the
+// same file still has to fetch, or nil when it has to start over
+func loadSegments(filename string, current resumeState) []segment {
+	var state resumeState
+	data, err := os.ReadFile(resumeStateFilename(filename))
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil || info.Size() != current.Size || state.URL != current.URL || state.Size != current.Size ||
+		current.validator() == "" || state.validator() != current.validator() || len(state.Remaining) == 0 {
+		return nil
+	}
+	for _, seg := range state.Remaining {
+		if seg.Start < 0 || seg.Start > seg.End || seg.End >= current.Size {
+			return nil
+		}
+	}
+	return state.Remaining
+}
+
+// downloadSegment retries a failed range from where it stopped and returns
+// the part of the range that is still missing when it gives up
+func (d *SegmentedDownloader) downloadSegment(url, validator string, seg segment, out io.WriterAt, progress ProgressTracker, retry func(attempt int, err error)) (segment, error) {
+	var err error
+	for attempt := 0; attempt <= d.MaxRetries; attempt++ {
+		var written int64
+		written, err = fetchRange(url, validator, seg, out, progressWriter{progress}, d.RateLimit)
+		seg.Start += written
+		if err == nil {
+			return seg, nil
+		}
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			return seg, err
+		}
+		if attempt < d.MaxRetries {
+			retry(attempt+1, err)
+		}
+	}
+	return seg, err
+}
+
+func fetchRange(url, validator string, seg segment, out io.WriterAt, progress io.Writer, limit *RateLimiter) (int64, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", seg.Start, seg.End))
+	if validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		// A full response means the file changed since the size was checked
+		return 0, &statusError{resp.StatusCode}
+	}
+	start, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || start != seg.Start {
+		return 0, fmt.Errorf("error downloading: unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+	}
+
+	length := seg.End - seg.Start + 1
+	w := io.NewOffsetWriter(out, seg.Start)
+	written, err := io.Copy(io.MultiWriter(w, progress), io.LimitReader(limit.Reader(resp.Body), length))
+	if err != nil {
+		return written, fmt.Errorf("error saving file: %w", err)
+	}
+	if written != length {
+		return written, fmt.Errorf("error saving file: received %d of %d bytes", written, length)
+	}
+	return written, nil
+}
+
+// splitSegments divides size bytes into at most n inclusive byte ranges
+func splitSegments(size int64, n int) []segment {
+	if limit := size / minSegmentSize; int64(n) > limit {
+		n = int(limit)
+	}
+	if n < 1 {
+		n = 1
+	}
+	chunk := size / int64(n)
+	segments := make([]segment, 0, n)
+	for i := 0; i < n; i++ {
+		start := int64(i) * chunk
+		end := start + chunk - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		segments = append(segments, segment{Start: start, End: end})
+	}
+	return segments
+}
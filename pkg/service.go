@@ -2,18 +2,31 @@ package pkg
 
 import (
 	"io"
+	"os"
 )
 
 type VersionService struct {
 	Downloader FileDownloader
 	Remover    FileRemover
 	Checksum   ChecksumCalculator
+	Cache      *ArchiveCache
+	Verifier   Verifier
 	Input      io.Reader
 	Output     io.Writer
+	Strict     bool
+	Kind       string
+	Prompt     bool
 }
 
 func (v *VersionService) GetCurrentVersion(versionFile, currentVersion string) (string, error) {
-	return GetCurrentVersion(versionFile, currentVersion)
+	if versionFile == StdinSource {
+		input := v.Input
+		if input == nil {
+			input = os.Stdin
+		}
+		return ReadVersionFromReader(input, ExtractOptions{Strict: v.Strict})
+	}
+	return GetCurrentVersionWithOptions(versionFile, currentVersion, ExtractOptions{Strict: v.Strict})
 }
 
 func (v *VersionService) GetLatestVersion() (string, error) {
@@ -25,7 +38,17 @@ func (v *VersionService) IsNewer(latestVersion, currentVersion string) bool {
 }
 
 func (v *VersionService) DownloadGo(version, targetOS, arch, path string, input io.Reader, output io.Writer) error {
-	config := DownloadConfig{
+	return DownloadGo(v.downloadConfig(version, targetOS, arch, path, input, output))
+}
+
+// Install downloads the archive of version for this machine and installs it
+// into goroot
+func (v *VersionService) Install(version, goroot string, output io.Writer) error {
+	return Install(v.downloadConfig(version, "", "", "", v.Input, output), goroot)
+}
+
+func (v *VersionService) downloadConfig(version, targetOS, arch, path string, input io.Reader, output io.Writer) DownloadConfig {
+	return DownloadConfig{
 		Version:    version,
 		TargetOS:   targetOS,
 		Arch:       arch,
@@ -33,8 +56,11 @@ func (v *VersionService) DownloadGo(version, targetOS, arch, path string, input
 		Downloader: v.Downloader,
 		Remover:    v.Remover,
 		Checksum:   v.Checksum,
+		Cache:      v.Cache,
+		Verifier:   v.Verifier,
+		Kind:       v.Kind,
+		Prompt:     v.Prompt,
 		Input:      input,
 		Output:     output,
 	}
-	return DownloadGo(config)
 }
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StdinSource is the version file name that reads content from standard input
+const StdinSource = "-"
+
+// CombineMode decides how versions read from several sources are reduced to one
+type CombineMode string
+
+const (
+	CombineLowest  CombineMode = "lowest"
+	CombineHighest CombineMode = "highest"
+	CombineError   CombineMode = "error"
+)
+
+type SourceVersion struct {
+	Source  string
+	Version string
+}
+
+func ParseCombineMode(mode string) (CombineMode, error) {
+	switch CombineMode(strings.ToLower(mode)) {
+	case "", CombineLowest:
+		return CombineLowest, nil
+	case CombineHighest:
+		return CombineHighest, nil
+	case CombineError:
+		return CombineError, nil
+	}
+	return "", fmt.Errorf("unknown combine mode %q (expected lowest, highest or error)", mode)
+}
+
+// ExpandVersionFiles expands glob patterns into the matching files and
+// directories into the Go binaries found under them. Other paths and "-"
+// are kept as they are so reading them reports its own error.
+func ExpandVersionFiles(patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		if pattern == StdinSource && seen[StdinSource] {
+			return nil, fmt.Errorf("standard input (-) can only be read once")
+		}
+
+		matches := []string{pattern}
+		if fi, err := os.Stat(pattern); err == nil && fi.IsDir() {
+			binaries, err := FindGoBinaries(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if len(binaries) == 0 {
+				return nil, fmt.Errorf("no Go binaries found in %s", pattern)
+			}
+			matches = matches[:0]
+			for _, info := range binaries {
+				matches = append(matches, info.Path)
+			}
+		} else if pattern != StdinSource && strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match pattern %q", pattern)
+			}
+			sort.Strings(matches)
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+func CombineVersions(versions []SourceVersion, mode CombineMode) (string, error) {
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no versions to combine")
+	}
+
+	result := versions[0].Version
+	for _, sv := range versions[1:] {
+		switch mode {
+		case CombineHighest:
+			if IsNewer(sv.Version, result) {
+				result = sv.Version
+			}
+		case CombineError:
+			if normalizeVersion(sv.Version) != normalizeVersion(result) {
+				return "", fmt.Errorf("version sources disagree: %s", formatSourceVersions(versions))
+			}
+		default:
+			if IsNewer(result, sv.Version) {
+				result = sv.Version
+			}
+		}
+	}
+	return result, nil
+}
+
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "go")
+}
+
+func formatSourceVersions(versions []SourceVersion) string {
+	parts := make([]string, 0, len(versions))
+	for _, sv := range versions {
+		parts = append(parts, fmt.Sprintf("%s=%s", sv.Source, sv.Version))
+	}
+	return strings.Join(parts, ", ")
+}
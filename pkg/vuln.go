@@ -0,0 +1,245 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VulnDBURL is the Go vulnerability database. A local mirror with the same
+// layout (index/modules.json and ID/<id>.json) can be used for offline audits.
+var VulnDBURL = "https://vuln.go.dev"
+
+type Vulnerability struct {
+	ID      string `json:"id"`
+	Summary string `json:"summary,omitempty"`
+	Fixed   string `json:"fixed,omitempty"`
+}
+
+type osvEntry struct {
+	ID       string `json:"id"`
+	Summary  string `json:"summary"`
+	Affected []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		Ranges []osvRange `json:"ranges"`
+	} `json:"affected"`
+}
+
+type osvRange struct {
+	Type   string `json:"type"`
+	Events []struct {
+		Introduced string `json:"introduced,omitempty"`
+		Fixed      string `json:"fixed,omitempty"`
+	} `json:"events"`
+}
+
+type vulnIndexModule struct {
+	Path  string `json:"path"`
+	Vulns []struct {
+		ID string `json:"id"`
+	} `json:"vulns"`
+}
+
+// VulnDB holds the standard library entries of the Go vulnerability database
+type VulnDB struct {
+	entries []osvEntry
+}
+
+// LoadVulnDB reads the standard library entries from a vulnerability
+// database given as an http(s) URL or a local directory
+func LoadVulnDB(source string) (*VulnDB, error) {
+	read := vulnDBReader(source)
+	data, err := read("index/modules.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vulnerability index: %w", err)
+	}
+	var modules []vulnIndexModule
+	if err := json.Unmarshal(data, &modules); err != nil {
+		return nil, fmt.Errorf("failed to parse vulnerability index: %w", err)
+	}
+
+	db := &VulnDB{}
+	for _, module := range modules {
+		if module.Path != "stdlib" {
+			continue
+		}
+		for _, v := range module.Vulns {
+			data, err := read("ID/" + v.ID + ".json")
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", v.ID, err)
+			}
+			var entry osvEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", v.ID, err)
+			}
+			db.entries = append(db.entries, entry)
+		}
+	}
+	return db, nil
+}
+
+func vulnDBReader(source string) func(name string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		base := strings.TrimSuffix(source, "/")
+		return func(name string) ([]byte, error) {
+			resp, err := http.Get(base + "/" + name)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+			}
+			return io.ReadAll(resp.Body)
+		}
+	}
+	dir := strings.TrimPrefix(source, "file://")
+	return func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+}
+
+// Vulnerabilities returns the standard library vulnerabilities affecting a Go release
+func (db *VulnDB) Vulnerabilities(goVersion string) []Vulnerability {
+	version := goSemver(goVersion)
+	if version == "" {
+		return nil
+	}
+
+	var vulns []Vulnerability
+	for _, entry := range db.entries {
+		for _, affected := range entry.Affected {
+			if affected.Package.Name != "stdlib" {
+				continue
+			}
+			if fixed, ok := affectedBy(version, affected.Ranges); ok {
+				vulns = append(vulns, Vulnerability{ID: entry.ID, Summary: entry.Summary, Fixed: fixed})
+				break
+			}
+		}
+	}
+	sort.Slice(vulns, func(i, j int) bool { return vulns[i].ID < vulns[j].ID })
+	return vulns
+}
+
+// AnnotateBinaries fills in the vulnerabilities for each binary's Go version
+func (db *VulnDB) AnnotateBinaries(infos []BinaryInfo) {
+	for i := range infos {
+		infos[i].Vulnerabilities = db.Vulnerabilities(infos[i].GoVersion)
+	}
+}
+
+// affectedBy evaluates OSV SEMVER ranges and returns the fixing version, if any
+func affectedBy(version string, ranges []osvRange) (string, bool) {
+	for _, r := range ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		affected, fixed := false, ""
+		for _, event := range r.Events {
+			switch {
+			case event.Introduced != "":
+				if event.Introduced == "0" || compareSemver(version, event.Introduced) >= 0 {
+					affected, fixed = true, ""
+				}
+			case event.Fixed != "":
+				if compareSemver(version, event.Fixed) >= 0 {
+					affected = false
+				} else if affected && fixed == "" {
+					fixed = event.Fixed
+				}
+			}
+		}
+		if affected {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+var goReleasePattern = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?(?:(rc|beta)(\d+))?$`)
+
+// goSemver converts a Go release such as 1.22rc1 or 1.21.5 into the
+// semantic version used by the vulnerability database
+func goSemver(goVersion string) string {
+	m := goReleasePattern.FindStringSubmatch(strings.TrimPrefix(goVersion, "go"))
+	if m == nil {
+		return ""
+	}
+	patch := m[3]
+	if patch == "" {
+		patch = "0"
+	}
+	v := m[1] + "." + m[2] + "." + patch
+	if m[4] != "" {
+		v += "-" + m[4] + "." + m[5]
+	}
+	return v
+}
+
+func compareSemver(a, b string) int {
+	aMain, aPre, _ := strings.Cut(a, "-")
+	bMain, bPre, _ := strings.Cut(b, "-")
+	aParts := strings.Split(aMain, ".")
+	bParts := strings.Split(bMain, ".")
+	for i := 0; i < 3; i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePrerelease(aPre, bPre)
+}
+
+func comparePrerelease(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		x, errX := strconv.Atoi(aParts[i])
+		y, errY := strconv.Atoi(bParts[i])
+		switch {
+		case errX == nil && errY == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case aParts[i] != bParts[i]:
+			if errX == nil {
+				return -1
+			}
+			if errY == nil {
+				return 1
+			}
+			return strings.Compare(aParts[i], bParts[i])
+		}
+	}
+	return len(aParts) - len(bParts)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResolvePlatforms(t *testing.T) {
+	tests := []struct {
+		name          string
+		platforms     string
+		oses          string
+		arches        string
+		expected      []pkg.Platform
+		expectedError string
+	}{
+		{name: "single platform", oses: "linux", arches: "amd64"},
+		{name: "no platform", oses: "", arches: ""},
+		{
+			name:      "platform list",
+			platforms: "linux/amd64, darwin/arm64,linux/amd64",
+			expected:  []pkg.Platform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}},
+		},
+		{
+			name:   "os and arch lists",
+			oses:   "linux,darwin",
+			arches: "amd64,arm64",
+			expected: []pkg.Platform{
+				{OS: "linux", Arch: "amd64"}, {OS: "linux", Arch: "arm64"},
+				{OS: "darwin", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"},
+			},
+		},
+		{name: "aliases", platforms: "macos/aarch64,linux/x86_64", expected: []pkg.Platform{{OS: "darwin", Arch: "arm64"}, {OS: "linux", Arch: "amd64"}}},
+		{name: "os list with one arch", oses: "linux,windows", arches: "amd64", expected: []pkg.Platform{{OS: "linux", Arch: "amd64"}, {OS: "windows", Arch: "amd64"}}},
+		{name: "list without arch", oses: "linux,windows", expectedError: "lists of operating systems and architectures need both -os and -arch"},
+		{name: "invalid pair", platforms: "linux", expectedError: `invalid platform "linux": must be os/arch`},
+		{name: "platforms with os", platforms: "linux/amd64", oses: "linux", expectedError: "-platforms cannot be combined with -os or -arch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platforms, err := pkg.ResolvePlatforms(tt.platforms, tt.oses, tt.arches)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, platforms)
+		})
+	}
+}
+
+func TestRunBatchLimitsConcurrency(t *testing.T) {
+	var platforms []pkg.Platform
+	for i := 0; i < 10; i++ {
+		platforms = append(platforms, pkg.Platform{OS: "linux", Arch: fmt.Sprintf("arch%d", i)})
+	}
+
+	var running, peak int32
+	output := &bytes.Buffer{}
+	results := pkg.RunBatch(platforms, 3, output, func(p pkg.Platform, w io.Writer) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		fmt.Fprintf(w, "downloaded %s\n", p)
+		if p.Arch == "arch4" {
+			return errors.New("checksum mismatch")
+		}
+		return nil
+	})
+
+	assert.LessOrEqual(t, peak, int32(3))
+	assert.Len(t, results, 10)
+	for i, r := range results {
+		assert.Equal(t, platforms[i], r.Platform)
+	}
+	assert.EqualError(t, results[4].Err, "checksum mismatch")
+	assert.Contains(t, output.String(), "[linux/arch7] downloaded linux/arch7\n")
+
+	summary := &bytes.Buffer{}
+	err := pkg.WriteBatchSummary(summary, results)
+	assert.EqualError(t, err, "1 of 10 downloads failed")
+	assert.Contains(t, summary.String(), "linux/arch4      failed: checksum mismatch")
+	assert.Contains(t, summary.String(), "9 of 10 downloads succeeded")
+}
+
+func TestRunWithConfigDownloadsPlatforms(t *testing.T) {
+	mockService := new(MockVersionChecker)
+	mockService.On("GetCurrentVersion", "", "1.21.0").Return("1.21.0", nil)
+	mockService.On("GetLatestVersion").Return("1.22.5", nil)
+	mockService.On("IsNewer", "1.22.5", "1.21.0").Return(true)
+	mockService.On("DownloadGo", "1.22.5", "linux", "amd64", "/tmp", mock.Anything, mock.Anything).Return(nil)
+	mockService.On("DownloadGo", "1.22.5", "darwin", "arm64", "/tmp", mock.Anything, mock.Anything).Return(errors.New("unsupported"))
+
+	output := &bytes.Buffer{}
+	err := pkg.RunWithConfig(mockService, pkg.RunConfig{
+		CurrentVersion: "1.21.0",
+		Platforms:      []pkg.Platform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}},
+		Input:          strings.NewReader("yes\n/tmp\n"),
+		Output:         output,
+	})
+
+	assert.EqualError(t, err, "error downloading Go: 1 of 2 downloads failed")
+	assert.Contains(t, output.String(), "Downloading 1.22.5 for 2 platforms")
+	assert.Contains(t, output.String(), "1 of 2 downloads succeeded")
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyTestBinary copies the running test executable, which is a Go binary with build info
+func copyTestBinary(t *testing.T, dst string) {
+	t.Helper()
+	self, err := os.Executable()
+	require.NoError(t, err)
+	in, err := os.Open(self)
+	require.NoError(t, err)
+	defer in.Close()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0o755))
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	require.NoError(t, err)
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	require.NoError(t, err)
+}
+
+func runtimeGoVersion() string {
+	return strings.TrimPrefix(strings.Fields(runtime.Version())[0], "go")
+}
+
+func TestIsExecutableHeader(t *testing.T) {
+	assert.True(t, pkg.IsExecutableHeader([]byte("\x7fELF\x02\x01")))
+	assert.True(t, pkg.IsExecutableHeader([]byte("MZ\x90\x00")))
+	assert.True(t, pkg.IsExecutableHeader([]byte{0xcf, 0xfa, 0xed, 0xfe}))
+	assert.False(t, pkg.IsExecutableHeader([]byte("go 1.22")))
+	assert.False(t, pkg.IsExecutableHeader(nil))
+}
+
+func TestReadBinaryInfo(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "app")
+	copyTestBinary(t, bin)
+
+	info, err := pkg.ReadBinaryInfo(bin)
+	require.NoError(t, err)
+	assert.Equal(t, bin, info.Path)
+	assert.Equal(t, runtimeGoVersion(), info.GoVersion)
+	assert.NotEmpty(t, info.ModulePath)
+	assert.Equal(t, runtime.GOOS, info.Settings["GOOS"])
+
+	version, err := pkg.ReadVersionFromFile(bin)
+	require.NoError(t, err)
+	assert.Equal(t, runtimeGoVersion(), version)
+
+	text := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(text, []byte("go 1.22"), 0o644))
+	_, err = pkg.ReadBinaryInfo(text)
+	assert.ErrorContains(t, err, "failed to read Go build info")
+}
+
+func TestFindGoBinaries(t *testing.T) {
+	root := t.TempDir()
+	copyTestBinary(t, filepath.Join(root, "bin", "server"))
+	copyTestBinary(t, filepath.Join(root, "tools", "cli"))
+	writeFiles(t, root, map[string]string{
+		"bin/start.sh": "#!/bin/sh\nexec ./server\n",
+		"bin/fake":     "\x7fELF but not really",
+	})
+
+	infos, err := pkg.FindGoBinaries(root)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, filepath.Join(root, "bin", "server"), infos[0].Path)
+	assert.Equal(t, filepath.Join(root, "tools", "cli"), infos[1].Path)
+
+	files, err := pkg.ExpandVersionFiles([]string{root})
+	require.NoError(t, err)
+	assert.Equal(t, []string{infos[0].Path, infos[1].Path}, files)
+
+	_, err = pkg.ExpandVersionFiles([]string{t.TempDir()})
+	assert.ErrorContains(t, err, "no Go binaries found in")
+}
+
+func TestWriteBinaryReport(t *testing.T) {
+	infos := []pkg.BinaryInfo{
+		{Path: "bin/old", GoVersion: "1.21.5", ModulePath: "example.com/old"},
+		{Path: "bin/new", GoVersion: "1.23.2", ModulePath: "example.com/new"},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, pkg.WriteBinaryReport(&out, infos, "go1.23.2"))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[1], "bin/old")
+	assert.True(t, strings.HasSuffix(lines[1], "outdated"))
+	assert.True(t, strings.HasSuffix(lines[2], "up to date"))
+
+	out.Reset()
+	require.NoError(t, pkg.WriteBinaryReport(&out, infos, ""))
+	assert.Contains(t, out.String(), "unknown")
+}
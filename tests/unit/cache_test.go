@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// storeInCache adds an archive with the given content to the cache and
+// marks it as last used at the given time
+func storeInCache(t *testing.T, cache *pkg.ArchiveCache, filename, content string, lastUsed time.Time) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), filename)
+	assert.NoError(t, os.WriteFile(src, []byte(content), 0o644))
+	sum := sha256Hex(content)
+	assert.NoError(t, cache.Store(src, filename, sum))
+	assert.NoError(t, os.Chtimes(filepath.Join(cache.Dir, sum, filename), lastUsed, lastUsed))
+	return sum
+}
+
+func TestArchiveCacheLookup(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	sum := storeInCache(t, cache, "go1.22.5.linux-amd64.tar.gz", "archive", time.Now())
+
+	path, ok := cache.Lookup("go1.22.5.linux-amd64.tar.gz", sum)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(cache.Dir, sum, "go1.22.5.linux-amd64.tar.gz"), path)
+
+	_, ok = cache.Lookup("go1.22.5.linux-amd64.tar.gz", sha256Hex("other"))
+	assert.False(t, ok)
+	_, ok = cache.Lookup("go1.22.5.linux-arm64.tar.gz", sum)
+	assert.False(t, ok)
+}
+
+func TestArchiveCacheLookupRemovesCorruptEntry(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	sum := storeInCache(t, cache, "go.tar.gz", "archive", time.Now())
+	path := filepath.Join(cache.Dir, sum, "go.tar.gz")
+	assert.NoError(t, os.Remove(path))
+	assert.NoError(t, os.WriteFile(path, []byte("corrupt"), 0o644))
+
+	_, ok := cache.Lookup("go.tar.gz", sum)
+	assert.False(t, ok)
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestArchiveCacheListVerifyPrune(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	now := time.Now()
+	storeInCache(t, cache, "old.tar.gz", "0123456789", now.Add(-2*time.Hour))
+	storeInCache(t, cache, "new.tar.gz", "01234", now)
+	corruptSum := storeInCache(t, cache, "mid.tar.gz", "abcdefgh", now.Add(-time.Hour))
+	corrupt := filepath.Join(cache.Dir, corruptSum, "mid.tar.gz")
+	assert.NoError(t, os.Remove(corrupt))
+	assert.NoError(t, os.WriteFile(corrupt, []byte("ABCDEFGH"), 0o644))
+	assert.NoError(t, os.Chtimes(corrupt, now.Add(-time.Hour), now.Add(-time.Hour)))
+
+	entries, err := cache.List()
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Filename)
+	}
+	assert.Equal(t, []string{"new.tar.gz", "mid.tar.gz", "old.tar.gz"}, names)
+
+	verified, err := cache.Verify()
+	assert.NoError(t, err)
+	valid := map[string]bool{}
+	for _, e := range verified {
+		valid[e.Filename] = *e.Valid
+	}
+	assert.Equal(t, map[string]bool{"new.tar.gz": true, "mid.tar.gz": false, "old.tar.gz": true}, valid)
+
+	removed, err := cache.Prune(13)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "old.tar.gz", removed[0].Filename)
+
+	entries, err = cache.List()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestArchiveCacheListMissingDir(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: filepath.Join(t.TempDir(), "missing")}
+	entries, err := cache.List()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDownloadGoUsesCache(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	sum := storeInCache(t, cache, "go1.22.5.linux-amd64.tar.gz", "cached archive", time.Now())
+
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+	mockChecksum.On("GetOfficialChecksum", "go1.22.5.linux-amd64.tar.gz").Return(sum, nil)
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Cache:      cache,
+		Output:     output,
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "Using cached archive")
+
+	content, err := os.ReadFile(filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+	assert.NoError(t, err)
+	assert.Equal(t, "cached archive", string(content))
+	mockDownloader.AssertNotCalled(t, "Download", mock.Anything, mock.Anything)
+}
+
+func TestDownloadGoStoresInCache(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+
+	sum := sha256Hex("archive")
+	partial := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz.part")
+	mockChecksum.On("GetOfficialChecksum", "go1.22.5.linux-amd64.tar.gz").Return(sum, nil)
+	mockDownloader.On("Download", mock.Anything, partial).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", partial).Return(sum, nil)
+
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Cache:      cache,
+		Output:     &bytes.Buffer{},
+	})
+	assert.NoError(t, err)
+
+	path, ok := cache.Lookup("go1.22.5.linux-amd64.tar.gz", sum)
+	assert.True(t, ok)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", string(content))
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"500", 500},
+		{"64K", 64 << 10},
+		{"5M", 5 << 20},
+		{"5mb", 5 << 20},
+		{"1.5G", 3 << 29},
+		{"2GiB", 2 << 30},
+		{"10B", 10},
+	}
+	for _, tt := range tests {
+		size, err := pkg.ParseByteSize(tt.input)
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, size, tt.input)
+	}
+
+	_, err := pkg.ParseByteSize("lots")
+	assert.EqualError(t, err, `invalid size "lots"`)
+	_, err = pkg.ParseByteSize("-1M")
+	assert.Error(t, err)
+}
+
+func TestArchiveCacheRemoveVersion(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	storeInCache(t, cache, "go1.22.5.linux-amd64.tar.gz", "a", time.Now())
+	storeInCache(t, cache, "go1.22.5.src.tar.gz", "b", time.Now())
+	storeInCache(t, cache, "go1.22.50.linux-amd64.tar.gz", "c", time.Now())
+	storeInCache(t, cache, "go1.21.0.linux-amd64.tar.gz", "d", time.Now())
+
+	removed, err := cache.RemoveVersion("go1.22.5")
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range removed {
+		names = append(names, e.Filename)
+	}
+	assert.ElementsMatch(t, []string{"go1.22.5.linux-amd64.tar.gz", "go1.22.5.src.tar.gz"}, names)
+
+	entries, err := cache.List()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestArchiveCacheRemoveVersionKeepsPatchReleases(t *testing.T) {
+	cache := &pkg.ArchiveCache{Dir: t.TempDir()}
+	storeInCache(t, cache, "go1.20.linux-amd64.tar.gz", "a", time.Now())
+	storeInCache(t, cache, "go1.20.windows-amd64.msi", "b", time.Now())
+	storeInCache(t, cache, "go1.20.14.linux-amd64.tar.gz", "c", time.Now())
+	storeInCache(t, cache, "go1.20.14.src.tar.gz", "d", time.Now())
+
+	removed, err := cache.RemoveVersion("1.20")
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range removed {
+		names = append(names, e.Filename)
+	}
+	assert.ElementsMatch(t, []string{"go1.20.linux-amd64.tar.gz", "go1.20.windows-amd64.msi"}, names)
+
+	entries, err := cache.List()
+	assert.NoError(t, err)
+	names = nil
+	for _, e := range entries {
+		names = append(names, e.Filename)
+	}
+	assert.ElementsMatch(t, []string{"go1.20.14.linux-amd64.tar.gz", "go1.20.14.src.tar.gz"}, names)
+}
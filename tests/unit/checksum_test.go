@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Nicconike/AutomatedGo/v2/pkg"
@@ -17,13 +19,7 @@ func createServerFunc(filename, sha256 string) func(http.ResponseWriter, *http.R
 		releases := []pkg.GoRelease{
 			{
 				Version: "go1.22.5",
-				Files: []struct {
-					Filename string `json:"filename"`
-					OS       string `json:"os"`
-					Arch     string `json:"arch"`
-					Version  string `json:"version"`
-					SHA256   string `json:"sha256"`
-				}{
+				Files: []pkg.ReleaseFile{
 					{
 						Filename: filename,
 						SHA256:   sha256,
@@ -241,3 +237,36 @@ func TestCalculateFileChecksum(t *testing.T) {
 		assertErrorForInaccessibleFile(t, calculator)
 	})
 }
+
+func TestDefaultChecksumCalculatorFetchesIndexOnce(t *testing.T) {
+	var requests atomic.Int32
+	serve := createServerFunc("go1.22.5.linux-amd64.tar.gz", "abc123")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		serve(w, r)
+	}))
+	defer server.Close()
+	pkg.URL = server.URL
+
+	calculator := &pkg.DefaultChecksumCalculator{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := calculator.GetOfficialChecksum("go1.22.5.linux-amd64.tar.gz")
+			assertChecksumResult(t, got, err, "abc123", "")
+		}()
+	}
+	wg.Wait()
+	if n := requests.Load(); n != 1 {
+		t.Errorf("concurrent lookups fetched the release index %d times, want 1", n)
+	}
+
+	// Each calculator keeps its own copy of the index
+	got, err := (&pkg.DefaultChecksumCalculator{}).GetOfficialChecksum("go1.22.5.linux-amd64.tar.gz")
+	assertChecksumResult(t, got, err, "abc123", "")
+	if n := requests.Load(); n != 2 {
+		t.Errorf("a second calculator fetched the release index %d times in total, want 2", n)
+	}
+}
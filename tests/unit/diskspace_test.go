@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, pkg.CheckWritable(dir))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "the probe file should be removed")
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced")
+	}
+	readOnly := filepath.Join(dir, "readonly")
+	assert.NoError(t, os.Mkdir(readOnly, 0o555))
+	err = pkg.CheckWritable(readOnly)
+	assert.ErrorContains(t, err, "cannot write to "+readOnly)
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, pkg.CheckDiskSpace(dir, 0))
+	assert.NoError(t, pkg.CheckDiskSpace(dir, 1))
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skip("free space is not available on this platform")
+	}
+	err := pkg.CheckDiskSpace(dir, 1<<62)
+	assert.ErrorContains(t, err, "not enough free space in "+dir+": 4.0 EiB needed")
+}
+
+func TestDownloadGoFailsEarlyWithoutDiskSpace(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skip("free space is not available on this platform")
+	}
+	releases := []pkg.GoRelease{{
+		Version: "go1.22.5",
+		Files: []pkg.ReleaseFile{
+			{Filename: "go1.22.5.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Kind: "archive", SHA256: "checksum", Size: 1 << 62},
+		},
+	}}
+
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockReleaseIndex)
+	mockChecksum.On("Releases").Return(releases, nil)
+	mockChecksum.On("GetOfficialChecksum", "go1.22.5.linux-amd64.tar.gz").Return("checksum", nil)
+
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     &bytes.Buffer{},
+	})
+	assert.ErrorContains(t, err, "not enough free space in "+dir)
+	mockDownloader.AssertNotCalled(t, "Download", mock.Anything, mock.Anything)
+	assert.NoFileExists(t, filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz.part"))
+}
@@ -2,14 +2,19 @@ package tests
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Nicconike/AutomatedGo/v2/pkg"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +41,13 @@ func (m *MockRemover) Remove(filename string) error {
 	return args.Error(0)
 }
 
+// writeDownloadedFile makes a mocked download create the file it was asked for
+func writeDownloadedFile(args mock.Arguments) {
+	if err := os.WriteFile(args.String(1), []byte("archive"), 0o644); err != nil {
+		log.Printf("Failed to write downloaded file: %v", err)
+	}
+}
+
 func TestRemove(t *testing.T) {
 	t.Run("Remove existing file", func(t *testing.T) {
 		// Create a temporary file
@@ -156,7 +168,7 @@ func TestDownloadGo(t *testing.T) {
 			},
 			setupMocks: func(d *MockDownloader, r *MockRemover, c *MockChecksumCalculator) {
 				c.On("GetOfficialChecksum", mock.Anything).Return(checksum, nil)
-				d.On("Download", mock.Anything, mock.Anything).Return(nil)
+				d.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
 				c.On("Calculate", mock.Anything).Return(checksum, nil)
 			},
 			expectedError: nil,
@@ -166,12 +178,13 @@ func TestDownloadGo(t *testing.T) {
 			config: pkg.DownloadConfig{
 				Version: "1.18.5",
 				Path:    "",
+				Prompt:  true,
 				Input:   strings.NewReader("linux\namd64\n"),
 				Output:  &bytes.Buffer{},
 			},
 			setupMocks: func(d *MockDownloader, r *MockRemover, c *MockChecksumCalculator) {
 				c.On("GetOfficialChecksum", mock.Anything).Return(checksum, nil)
-				d.On("Download", mock.Anything, mock.Anything).Return(nil)
+				d.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
 				c.On("Calculate", mock.Anything).Return(checksum, nil)
 			},
 			expectedError: nil,
@@ -188,7 +201,7 @@ func TestDownloadGo(t *testing.T) {
 			},
 			setupMocks: func(d *MockDownloader, r *MockRemover, c *MockChecksumCalculator) {
 				c.On("GetOfficialChecksum", mock.Anything).Return("correct-checksum", nil)
-				d.On("Download", mock.Anything, mock.Anything).Return(nil)
+				d.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
 				c.On("Calculate", mock.Anything).Return("incorrect-checksum", nil)
 				r.On("Remove", mock.Anything).Return(nil)
 			},
@@ -207,13 +220,19 @@ func TestDownloadGo(t *testing.T) {
 			tt.config.Downloader = mockDownloader
 			tt.config.Remover = mockRemover
 			tt.config.Checksum = mockChecksumCalculator
+			tt.config.Path = t.TempDir()
 
 			err := pkg.DownloadGo(tt.config)
 
+			archives, _ := filepath.Glob(filepath.Join(tt.config.Path, "*.tar.gz"))
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Empty(t, archives, "no archive may be left under the final name")
 			} else {
 				assert.NoError(t, err)
+				assert.Len(t, archives, 1)
+				partials, _ := filepath.Glob(filepath.Join(tt.config.Path, "*.part"))
+				assert.Empty(t, partials)
 			}
 
 			mockDownloader.AssertExpectations(t)
@@ -222,3 +241,365 @@ func TestDownloadGo(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadGoWritesToPartialFileFirst(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "downloads")
+	mockDownloader := new(MockDownloader)
+	mockRemover := new(MockRemover)
+	mockChecksum := new(MockChecksumCalculator)
+
+	partial := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz.part")
+	mockChecksum.On("GetOfficialChecksum", "go1.22.5.linux-amd64.tar.gz").Return("checksum", nil)
+	mockDownloader.On("Download", "https://dl.google.com/go/go1.22.5.linux-amd64.tar.gz", partial).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", partial).Return("checksum", nil)
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "go1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    mockRemover,
+		Checksum:   mockChecksum,
+		Output:     output,
+	})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", string(content))
+	_, err = os.Stat(partial)
+	assert.True(t, os.IsNotExist(err))
+	assert.Contains(t, output.String(), "Saved "+filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+
+	mockDownloader.AssertExpectations(t)
+	mockRemover.AssertExpectations(t)
+	mockChecksum.AssertExpectations(t)
+}
+
+func TestDownloadGoKeepsPartialFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockRemover := new(MockRemover)
+	mockChecksum := new(MockChecksumCalculator)
+
+	partial := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz.part")
+	mockChecksum.On("GetOfficialChecksum", mock.Anything).Return("checksum", nil)
+	mockDownloader.On("Download", mock.Anything, partial).Return(errors.New("connection reset")).Run(writeDownloadedFile)
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    mockRemover,
+		Checksum:   mockChecksum,
+		Output:     output,
+	})
+	assert.EqualError(t, err, "connection reset")
+	assert.Contains(t, output.String(), "The partial download was kept in "+partial+", run the download again to resume it")
+	assert.FileExists(t, partial)
+
+	mockDownloader.AssertExpectations(t)
+	mockRemover.AssertNotCalled(t, "Remove", mock.Anything)
+	mockChecksum.AssertExpectations(t)
+}
+
+func TestDownloadGoFailureWithoutPartialFile(t *testing.T) {
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+	mockChecksum.On("GetOfficialChecksum", mock.Anything).Return("checksum", nil)
+	mockDownloader.On("Download", mock.Anything, mock.Anything).Return(errors.New("unexpected status code: 404"))
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       t.TempDir(),
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     output,
+	})
+	assert.EqualError(t, err, "unexpected status code: 404")
+	assert.NotContains(t, output.String(), "partial download")
+}
+
+// rangeServer serves content with an ETag and honours Range and If-Range
+// requests, recording the Range header of every request
+func rangeServer(t *testing.T, content string, etag *string, ranges *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", *etag)
+		http.ServeContent(w, r, "archive", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadResumesPartialFile(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	etag := `"v1"`
+	var ranges []string
+	server := rangeServer(t, content, &etag, &ranges)
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz.part")
+	assert.NoError(t, os.WriteFile(filename, []byte(content[:400]), 0o644))
+	assert.NoError(t, os.WriteFile(filename+".meta", []byte(`{"url":"`+server.URL+`","etag":"\"v1\"","size":1000}`), 0o644))
+
+	sum, err := (&pkg.DefaultDownloader{}).DownloadWithHash(server.URL, filename)
+	assert.NoError(t, err)
+
+	// The bytes already on disk are part of the checksum
+	assert.Equal(t, sha256Hex(content), sum)
+	assert.Equal(t, []string{"bytes=400-"}, ranges)
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	_, err = os.Stat(filename + ".meta")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadRestartsWhenFileChanged(t *testing.T) {
+	content := strings.Repeat("abcdefghij", 100)
+	etag := `"v2"`
+	var ranges []string
+	server := rangeServer(t, content, &etag, &ranges)
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz.part")
+	assert.NoError(t, os.WriteFile(filename, []byte(strings.Repeat("x", 400)), 0o644))
+	assert.NoError(t, os.WriteFile(filename+".meta", []byte(`{"url":"`+server.URL+`","etag":"\"v1\"","size":1000}`), 0o644))
+
+	assert.NoError(t, (&pkg.DefaultDownloader{}).Download(server.URL, filename))
+
+	// The If-Range validator no longer matches, so the server sends the whole file
+	assert.Equal(t, []string{"bytes=400-"}, ranges)
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestDownloadIgnoresPartialFileWithoutState(t *testing.T) {
+	content := "complete archive"
+	etag := `"v1"`
+	var ranges []string
+	server := rangeServer(t, content, &etag, &ranges)
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz.part")
+	assert.NoError(t, os.WriteFile(filename, []byte("stale"), 0o644))
+
+	assert.NoError(t, (&pkg.DefaultDownloader{}).Download(server.URL, filename))
+
+	assert.Equal(t, []string{""}, ranges)
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestDownloadRetriesInterruptedTransfer(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if len(ranges) == 1 {
+			// Promise the whole file but cut the connection half way
+			w.Header().Set("Content-Length", "1000")
+			_, _ = w.Write([]byte(content[:500]))
+			w.(http.Flusher).Flush()
+			if hj, ok := w.(http.Hijacker); ok {
+				conn, buf, _ := hj.Hijack()
+				_ = buf.Flush()
+				conn.Close()
+			}
+			return
+		}
+		http.ServeContent(w, r, "archive", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz.part")
+	assert.NoError(t, (&pkg.DefaultDownloader{MaxRetries: 2}).Download(server.URL, filename))
+
+	assert.Len(t, ranges, 2)
+	assert.Equal(t, "", ranges[0])
+	assert.True(t, strings.HasPrefix(ranges[1], "bytes="), ranges[1])
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestSegmentedDownload(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", 3<<16)
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mu.Unlock()
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "archive", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz")
+	assert.NoError(t, (&pkg.SegmentedDownloader{Connections: 4}).Download(server.URL, filename))
+
+	assert.ElementsMatch(t, []string{"bytes=0-1048575", "bytes=1048576-2097151", "bytes=2097152-3145727"}, ranges)
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestSegmentedDownloadResumesFailedSegments(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", 3<<16)
+	var mu sync.Mutex
+	var ranges []string
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			rng := r.Header.Get("Range")
+			ranges = append(ranges, rng)
+			fail := rng == "bytes=1048576-2097151" && !failed
+			failed = failed || fail
+			mu.Unlock()
+			if fail {
+				// Send the first 1000 bytes of the range, then drop the connection
+				w.Header().Set("Content-Range", "bytes 1048576-2097151/"+strconv.Itoa(len(content)))
+				w.Header().Set("Content-Length", "1048576")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write([]byte(content[1048576 : 1048576+1000]))
+				w.(http.Flusher).Flush()
+				conn, buf, _ := w.(http.Hijacker).Hijack()
+				_ = buf.Flush()
+				conn.Close()
+				return
+			}
+		}
+		http.ServeContent(w, r, "archive", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz.part")
+	downloader := &pkg.SegmentedDownloader{Connections: 4, Progress: pkg.QuietProgress{}}
+	assert.Error(t, downloader.Download(server.URL, filename))
+	assert.FileExists(t, filename, "the segments already written are kept")
+	assert.FileExists(t, filename+".meta")
+
+	ranges = nil
+	sum, err := downloader.DownloadWithHash(server.URL, filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bytes=1049576-2097151"}, ranges, "only the missing part is fetched again")
+	assert.Equal(t, sha256Hex(content), sum)
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.NoFileExists(t, filename+".meta")
+}
+
+func TestSegmentedDownloadFallsBackWithoutRangeSupport(t *testing.T) {
+	content := strings.Repeat("x", 3<<20)
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.Header.Get("Range"))
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(content))
+		}
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz")
+	assert.NoError(t, (&pkg.SegmentedDownloader{Connections: 4}).Download(server.URL, filename))
+
+	assert.Equal(t, []string{"HEAD ", "GET "}, requests)
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, len(content), len(data))
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// MockChecksumDownloader also reports the checksum of what it downloaded
+type MockChecksumDownloader struct {
+	MockDownloader
+}
+
+func (m *MockChecksumDownloader) DownloadWithHash(url, filename string) (string, error) {
+	args := m.Called(url, filename)
+	return args.String(0), args.Error(1)
+}
+
+func TestDownloadWithHash(t *testing.T) {
+	content := "file content"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	sum, err := (&pkg.DefaultDownloader{}).DownloadWithHash(server.URL, filepath.Join(t.TempDir(), "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, sha256Hex(content), sum)
+}
+
+func TestDownloadGoUsesChecksumFromDownload(t *testing.T) {
+	tests := []struct {
+		name          string
+		downloadHash  string
+		expectedError string
+	}{
+		{name: "matching checksum", downloadHash: "checksum"},
+		{name: "mismatched checksum", downloadHash: "other", expectedError: "Checksum mismatch: expected checksum, got other for file go1.22.5.linux-amd64.tar.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mockDownloader := new(MockChecksumDownloader)
+			mockRemover := new(MockRemover)
+			mockChecksum := new(MockChecksumCalculator)
+
+			partial := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz.part")
+			mockChecksum.On("GetOfficialChecksum", "go1.22.5.linux-amd64.tar.gz").Return("checksum", nil)
+			mockDownloader.On("DownloadWithHash", mock.Anything, partial).Return(tt.downloadHash, nil).Run(writeDownloadedFile)
+			if tt.expectedError != "" {
+				mockRemover.On("Remove", partial).Return(nil)
+			}
+
+			err := pkg.DownloadGo(pkg.DownloadConfig{
+				Version:    "1.22.5",
+				TargetOS:   "linux",
+				Arch:       "amd64",
+				Path:       dir,
+				Downloader: mockDownloader,
+				Remover:    mockRemover,
+				Checksum:   mockChecksum,
+				Output:     &bytes.Buffer{},
+			})
+
+			_, statErr := os.Stat(filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.True(t, os.IsNotExist(statErr))
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, statErr)
+			}
+			mockChecksum.AssertNotCalled(t, "Calculate", mock.Anything)
+			mockDownloader.AssertExpectations(t)
+			mockRemover.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractGoVersionTrace(t *testing.T) {
+	var steps []pkg.TraceStep
+	opts := pkg.ExtractOptions{Trace: func(s pkg.TraceStep) { steps = append(steps, s) }}
+
+	match, err := pkg.ExtractGoVersionMatch("FROM golang:1.21.5\nFROM alpine:3.19\n", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "1.21.5", match.Version)
+
+	require.Len(t, steps, 7)
+	assert.Equal(t, "JSON key", steps[0].Rule)
+	assert.Equal(t, "no match", steps[0].Reason)
+	assert.Equal(t, "Dockerfile FROM golang", steps[2].Rule)
+	assert.True(t, steps[2].Selected)
+	assert.Equal(t, []string{"1.21.5"}, steps[2].Matches)
+	assert.Equal(t, "go version key", steps[5].Rule)
+	assert.False(t, steps[5].Selected)
+	assert.Equal(t, []string{"1.21.5"}, steps[5].Matches)
+	assert.Equal(t, "ignored, an earlier rule already matched", steps[5].Reason)
+	assert.Equal(t, []string{"1.21.5", "3.19"}, steps[6].Matches)
+}
+
+func TestExtractGoVersionTraceStrict(t *testing.T) {
+	reasons := make(map[string]string)
+	opts := pkg.ExtractOptions{Strict: true, Trace: func(s pkg.TraceStep) { reasons[s.Rule] = s.Reason }}
+
+	match, err := pkg.ExtractGoVersionMatch("FROM golang:1.21.5\nFROM alpine:3.19\n", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "Dockerfile FROM golang", match.Rule)
+	assert.Equal(t, "selected", reasons["Dockerfile FROM golang"])
+	assert.Equal(t, "ignored, a higher confidence rule matched", reasons["go version key"])
+	assert.Equal(t, "rejected, low confidence matches are not accepted in strict mode", reasons["bare version number"])
+
+	_, err = pkg.ExtractGoVersionMatch("FROM golang:1.21.5\nARG GO_VERSION=1.22.0\n", opts)
+	assert.Error(t, err)
+	assert.Equal(t, "conflicts with other candidates of the same confidence", reasons["Dockerfile FROM golang"])
+	assert.Equal(t, "conflicts with other candidates of the same confidence", reasons["Dockerfile ARG GO_VERSION"])
+}
+
+func TestExplainVersion(t *testing.T) {
+	var out bytes.Buffer
+	match, err := pkg.ExplainVersion(&out, "go.mod", []byte("module example.com/app\n\ngo 1.22.1\n"), pkg.ExtractOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1.22.1", match.Version)
+	assert.Contains(t, out.String(), "Detecting Go version in go.mod (first matching rule wins)")
+	assert.Contains(t, out.String(), "2. go.mod directive [high confidence]\n")
+	assert.Contains(t, out.String(), "   matched 1.22.1: selected\n")
+	assert.Contains(t, out.String(), `Result: 1.22.1 from rule "go.mod directive" (high confidence)`)
+
+	out.Reset()
+	_, err = pkg.ExplainVersion(&out, "notes.txt", []byte("nothing to see"), pkg.ExtractOptions{Strict: true})
+	assert.ErrorIs(t, err, pkg.ErrNoVersionFound)
+	assert.Contains(t, out.String(), "(strict mode)")
+	assert.Contains(t, out.String(), "Result: unable to extract Go version")
+}
@@ -1,7 +1,10 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -141,3 +144,115 @@ func TestReadVersionFromFile(t *testing.T) {
 		t.Errorf("Expected 'unable to extract Go version from file' error, got %v with result %s", err, result)
 	}
 }
+
+func TestExtractGoVersionNestedJSON(t *testing.T) {
+	content := `{"name": "app", "toolchain": {"go_version": "1.22.3"}}`
+	if result := pkg.ExtractGoVersion(content); result != "1.22.3" {
+		t.Errorf("ExtractGoVersion() = %v, want 1.22.3", result)
+	}
+}
+
+func TestExtractGoVersionMatchStrict(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		expected        string
+		expectedRule    string
+		expectedConf    pkg.Confidence
+		expectError     bool
+		expectAmbiguous bool
+	}{
+		{"go.mod", "module example.com/app\n\ngo 1.22.1\n", "1.22.1", "go.mod directive", pkg.ConfidenceHigh, false, false},
+		{"Dockerfile", "FROM golang:1.21.5 AS build\nFROM alpine:3.19\n", "1.21.5", "Dockerfile FROM golang", pkg.ConfidenceHigh, false, false},
+		{"Nested JSON", `{"build": {"goVersion": "1.20.2"}}`, "1.20.2", "JSON key", pkg.ConfidenceHigh, false, false},
+		{"Version key", "golang_version: 1.18.0", "1.18.0", "go version key", pkg.ConfidenceMedium, false, false},
+		{"Toolchain name", "built with go1.22.5", "1.22.5", "go version key", pkg.ConfidenceMedium, false, false},
+		{"Spaced key", "Go version 1.21", "1.21", "go version key", pkg.ConfidenceMedium, false, false},
+		{"Word ending in go", "image: mongo:4.4\n", "", "", pkg.ConfidenceLow, true, false},
+		{"Word ending in go in a compose file", "services:\n  db:\n    image: mongo:6.0\n", "", "", pkg.ConfidenceLow, true, false},
+		{"Go in prose", "Install the go tool 1.75.0 or later", "", "", pkg.ConfidenceLow, true, false},
+		{"Only bare number", "FROM alpine:3.19", "", "", pkg.ConfidenceLow, true, false},
+		{"Changelog date", "Released 2024.10.15", "", "", pkg.ConfidenceLow, true, false},
+		{"No version", "nothing here", "", "", pkg.ConfidenceLow, true, false},
+		{"Ambiguous Dockerfile", "FROM golang:1.21.5\nARG GO_VERSION=1.22.0\n", "", "", pkg.ConfidenceLow, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := pkg.ExtractGoVersionMatch(tt.content, pkg.ExtractOptions{Strict: true})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ExtractGoVersionMatch() error = %v, expectError %v", err, tt.expectError)
+			}
+			var ambiguous *pkg.AmbiguousVersionError
+			if errors.As(err, &ambiguous) != tt.expectAmbiguous {
+				t.Errorf("ExtractGoVersionMatch() ambiguous error = %v, want %v", err, tt.expectAmbiguous)
+			}
+			if tt.expectError {
+				return
+			}
+			if match.Version != tt.expected || match.Rule != tt.expectedRule || match.Confidence != tt.expectedConf {
+				t.Errorf("ExtractGoVersionMatch() = %+v, want %s via %s (%s)", match, tt.expected, tt.expectedRule, tt.expectedConf)
+			}
+		})
+	}
+}
+
+func TestExtractGoVersionMatchNonStrictFallback(t *testing.T) {
+	match, err := pkg.ExtractGoVersionMatch("FROM alpine:3.19", pkg.ExtractOptions{})
+	if err != nil {
+		t.Fatalf("ExtractGoVersionMatch() unexpected error = %v", err)
+	}
+	if match.Version != "3.19" || match.Confidence != pkg.ConfidenceLow {
+		t.Errorf("ExtractGoVersionMatch() = %+v, want low-confidence 3.19", match)
+	}
+}
+
+func TestExtractGoVersionMatchPrefersHigherConfidence(t *testing.T) {
+	content := "# Built with go1.20 until the upgrade\nFROM golang:1.22 AS build\n"
+	match, err := pkg.ExtractGoVersionMatch(content, pkg.ExtractOptions{})
+	if err != nil {
+		t.Fatalf("ExtractGoVersionMatch() unexpected error = %v", err)
+	}
+	if match.Version != "1.22" || match.Rule != "Dockerfile FROM golang" {
+		t.Errorf("ExtractGoVersionMatch() = %+v, want 1.22 via Dockerfile FROM golang", match)
+	}
+}
+
+func TestAmbiguousVersionErrorListsCandidates(t *testing.T) {
+	tmpfile := createTempFile(t, "FROM golang:1.21.5\nENV GO_VERSION=1.22.0\n")
+	defer os.Remove(tmpfile.Name())
+
+	_, err := pkg.ReadVersionFromFileWithOptions(tmpfile.Name(), pkg.ExtractOptions{Strict: true})
+	if err == nil {
+		t.Fatal("Expected an ambiguity error, got nil")
+	}
+	for _, want := range []string{"1.21.5 (Dockerfile FROM golang, high confidence)", "1.22.0 (Dockerfile ENV GO_VERSION, high confidence)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestReadVersionFromPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("/dev/fd is not available")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	go func() {
+		_, _ = w.WriteString("go 1.22.5\n")
+		w.Close()
+	}()
+
+	// Like automatedgo -f <(printf 'go 1.22.5\n'), the content can only be read once
+	version, err := pkg.ReadVersionFromFile(fmt.Sprintf("/dev/fd/%d", r.Fd()))
+	if err != nil {
+		t.Fatalf("ReadVersionFromFile() unexpected error = %v", err)
+	}
+	if version != "1.22.5" {
+		t.Errorf("ReadVersionFromFile() = %v, want 1.22.5", version)
+	}
+}
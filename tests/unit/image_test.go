@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name    string
+	content []byte
+}
+
+func buildTar(t *testing.T, entries []tarEntry, compress bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o755, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.content == nil {
+			hdr.Typeflag = tar.TypeDir
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write(e.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	if gz != nil {
+		require.NoError(t, gz.Close())
+	}
+	return buf.Bytes()
+}
+
+func testBinaryBytes(t *testing.T) []byte {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "bin")
+	copyTestBinary(t, bin)
+	data, err := os.ReadFile(bin)
+	require.NoError(t, err)
+	return data
+}
+
+func imageLayersFixture(t *testing.T) ([]byte, []byte) {
+	bin := testBinaryBytes(t)
+	lower := buildTar(t, []tarEntry{
+		{"usr/", nil},
+		{"usr/bin/", nil},
+		{"usr/bin/app", bin},
+		{"usr/bin/old", bin},
+		{"opt/tools/a", bin},
+		{"etc/config", []byte("go_version: 1.10")},
+	}, true)
+	upper := buildTar(t, []tarEntry{
+		{"usr/bin/", nil},
+		{"usr/bin/.wh.old", []byte{}},
+		{"opt/tools/.wh..wh..opq", []byte{}},
+		{"usr/local/bin/tool", bin},
+	}, false)
+	return lower, upper
+}
+
+func writeImageTarball(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "image.tar")
+	require.NoError(t, os.WriteFile(path, buildTar(t, entries, false), 0o644))
+	return path
+}
+
+func assertImageBinaries(t *testing.T, infos []pkg.BinaryInfo, lowerLayer, upperLayer string) {
+	t.Helper()
+	require.Len(t, infos, 2)
+	assert.Equal(t, "/usr/bin/app", infos[0].Path)
+	assert.Equal(t, lowerLayer, infos[0].Layer)
+	assert.Equal(t, runtimeGoVersion(), infos[0].GoVersion)
+	assert.Equal(t, "/usr/local/bin/tool", infos[1].Path)
+	assert.Equal(t, upperLayer, infos[1].Layer)
+}
+
+func TestScanImageTarballDockerSave(t *testing.T) {
+	lower, upper := imageLayersFixture(t)
+	manifest, err := json.Marshal([]map[string]interface{}{
+		{"Config": "config.json", "RepoTags": []string{"app:latest"}, "Layers": []string{"aaa/layer.tar", "bbb/layer.tar"}},
+	})
+	require.NoError(t, err)
+
+	tarball := writeImageTarball(t, []tarEntry{
+		{"aaa/layer.tar", lower},
+		{"bbb/layer.tar", upper},
+		{"config.json", []byte(`{}`)},
+		{"manifest.json", manifest},
+	})
+
+	infos, err := pkg.ScanImageTarball(tarball)
+	require.NoError(t, err)
+	assertImageBinaries(t, infos, "aaa/layer.tar", "bbb/layer.tar")
+}
+
+func TestScanImageTarballOCILayout(t *testing.T) {
+	lower, upper := imageLayersFixture(t)
+	digest := func(data []byte) string { return fmt.Sprintf("sha256:%x", sha256.Sum256(data)) }
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"layers": []map[string]string{
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": digest(lower)},
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": digest(upper)},
+		},
+	})
+	require.NoError(t, err)
+	index, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests":     []map[string]string{{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": digest(manifest)}},
+	})
+	require.NoError(t, err)
+
+	blob := func(data []byte) string { return "blobs/sha256/" + digest(data)[len("sha256:"):] }
+	tarball := writeImageTarball(t, []tarEntry{
+		{"oci-layout", []byte(`{"imageLayoutVersion": "1.0.0"}`)},
+		{"index.json", index},
+		{blob(manifest), manifest},
+		{blob(lower), lower},
+		{blob(upper), upper},
+	})
+
+	infos, err := pkg.ScanImageTarball(tarball)
+	require.NoError(t, err)
+	assertImageBinaries(t, infos, blob(lower), blob(upper))
+}
+
+func TestScanImageTarballErrors(t *testing.T) {
+	_, err := pkg.ScanImageTarball(filepath.Join(t.TempDir(), "missing.tar"))
+	assert.Error(t, err)
+
+	notImage := writeImageTarball(t, []tarEntry{{"README", []byte("hello")}})
+	_, err = pkg.ScanImageTarball(notImage)
+	assert.ErrorContains(t, err, "not an image tarball")
+
+	missingLayer := writeImageTarball(t, []tarEntry{{"manifest.json", []byte(`[{"Layers": ["x/layer.tar"]}]`)}})
+	_, err = pkg.ScanImageTarball(missingLayer)
+	assert.ErrorContains(t, err, "layer x/layer.tar not found")
+}
@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type archiveEntry struct {
+	name     string
+	body     string
+	mode     int64
+	typeflag byte
+	linkname string
+}
+
+func goArchiveEntries(version string) []archiveEntry {
+	return []archiveEntry{
+		{name: "go/", typeflag: tar.TypeDir, mode: 0o755},
+		{name: "go/VERSION", body: "go" + version + "\ntime 2024-06-27T20:11:12Z\n", mode: 0o644},
+		{name: "go/bin/", typeflag: tar.TypeDir, mode: 0o755},
+		{name: "go/bin/go", body: "#!/bin/sh\n", mode: 0o755},
+		{name: "go/src/README", body: "source", mode: 0o444},
+	}
+}
+
+func writeTarGz(t *testing.T, entries []archiveEntry) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, Typeflag: typeflag, Linkname: e.linkname, Size: int64(len(e.body))}
+		if typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(e.body))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	path := filepath.Join(t.TempDir(), "go.linux-amd64.tar.gz")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
+
+func writeZip(t *testing.T, entries []archiveEntry) string {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		switch e.typeflag {
+		case tar.TypeDir:
+			hdr.SetMode(os.ModeDir | 0o755)
+		case tar.TypeSymlink:
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			e.body = e.linkname
+		default:
+			hdr.SetMode(os.FileMode(e.mode))
+		}
+		w, err := zw.CreateHeader(hdr)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	path := filepath.Join(t.TempDir(), "go.windows-amd64.zip")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
+
+func TestInstallArchive(t *testing.T) {
+	for name, write := range map[string]func(*testing.T, []archiveEntry) string{"tar.gz": writeTarGz, "zip": writeZip} {
+		t.Run(name, func(t *testing.T) {
+			entries := append(goArchiveEntries("1.22.5"), archiveEntry{name: "go/bin/gofmt", typeflag: tar.TypeSymlink, linkname: "go"})
+			archive := write(t, entries)
+			goroot := filepath.Join(t.TempDir(), "go")
+
+			assert.NoError(t, pkg.InstallArchive(archive, goroot, "1.22.5"))
+
+			version, err := pkg.ReadGOROOTVersion(goroot)
+			assert.NoError(t, err)
+			assert.Equal(t, "1.22.5", version)
+
+			target, err := os.Readlink(filepath.Join(goroot, "bin", "gofmt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "go", target)
+
+			if runtime.GOOS != "windows" {
+				info, err := os.Stat(filepath.Join(goroot, "bin", "go"))
+				assert.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+				info, err = os.Stat(filepath.Join(goroot, "src", "README"))
+				assert.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+				info, err = os.Stat(goroot)
+				assert.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestInstallArchiveReplacesPreviousInstallation(t *testing.T) {
+	goroot := filepath.Join(t.TempDir(), "go")
+	require.NoError(t, pkg.InstallArchive(writeTarGz(t, append(goArchiveEntries("1.21.0"), archiveEntry{name: "go/old.txt", body: "old", mode: 0o644})), goroot, "1.21.0"))
+
+	assert.NoError(t, pkg.InstallArchive(writeTarGz(t, goArchiveEntries("1.22.5")), goroot, "go1.22.5"))
+
+	version, err := pkg.ReadGOROOTVersion(goroot)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.22.5", version)
+	assert.NoFileExists(t, filepath.Join(goroot, "old.txt"))
+
+	entries, err := os.ReadDir(filepath.Dir(goroot))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "no staging or backup directory should be left behind")
+}
+
+func TestInstallArchiveVersionMismatch(t *testing.T) {
+	goroot := filepath.Join(t.TempDir(), "go")
+	require.NoError(t, pkg.InstallArchive(writeTarGz(t, goArchiveEntries("1.21.0")), goroot, "1.21.0"))
+
+	err := pkg.InstallArchive(writeTarGz(t, goArchiveEntries("1.22.4")), goroot, "1.22.5")
+	assert.EqualError(t, err, "archive go.linux-amd64.tar.gz contains Go 1.22.4, expected 1.22.5")
+
+	version, err := pkg.ReadGOROOTVersion(goroot)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.21.0", version, "the previous installation should be kept")
+	entries, err := os.ReadDir(filepath.Dir(goroot))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestInstallArchiveRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name          string
+		entry         archiveEntry
+		expectedError string
+	}{
+		{name: "parent directory", entry: archiveEntry{name: "go/../evil", body: "x", mode: 0o644}, expectedError: `unexpected entry "go/../evil" outside the go directory`},
+		{name: "outside go directory", entry: archiveEntry{name: "evil", body: "x", mode: 0o644}, expectedError: `unexpected entry "evil" outside the go directory`},
+		{name: "absolute path", entry: archiveEntry{name: "/go/evil", body: "x", mode: 0o644}, expectedError: `unexpected entry "/go/evil" outside the go directory`},
+		{name: "symlink escape", entry: archiveEntry{name: "go/bin/escape", typeflag: tar.TypeSymlink, linkname: "../../../etc"}, expectedError: `symlink "go/bin/escape" points outside the installation: "../../../etc"`},
+		{name: "absolute symlink", entry: archiveEntry{name: "go/bin/escape", typeflag: tar.TypeSymlink, linkname: "/etc"}, expectedError: `symlink "go/bin/escape" points outside the installation: "/etc"`},
+		{name: "hard link", entry: archiveEntry{name: "go/bin/link", typeflag: tar.TypeLink, linkname: "go/bin/go"}, expectedError: `unsupported entry "go/bin/link"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := writeTarGz(t, append(goArchiveEntries("1.22.5"), tt.entry))
+			goroot := filepath.Join(t.TempDir(), "go")
+
+			err := pkg.InstallArchive(archive, goroot, "1.22.5")
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.NoDirExists(t, goroot)
+		})
+	}
+}
+
+func TestInstallArchiveRejectsWritesThroughSymlinks(t *testing.T) {
+	archive := writeTarGz(t, append(goArchiveEntries("1.22.5"),
+		archiveEntry{name: "go/lib", typeflag: tar.TypeSymlink, linkname: "bin"},
+		archiveEntry{name: "go/lib/evil", body: "x", mode: 0o644},
+	))
+	err := pkg.InstallArchive(archive, filepath.Join(t.TempDir(), "go"), "1.22.5")
+	assert.ErrorContains(t, err, `entry "go/lib/evil" is inside a symlink`)
+}
+
+func TestInstallArchiveRejectsSymlinkChains(t *testing.T) {
+	link := archiveEntry{name: "go/sub/a", typeflag: tar.TypeSymlink, linkname: ".."}
+	escape := archiveEntry{name: "go/sub/e", typeflag: tar.TypeSymlink, linkname: "a/../../secret"}
+	tests := []struct {
+		name    string
+		entries []archiveEntry
+	}{
+		// As text a/../../secret is sub/secret, but a is the go directory
+		{name: "through an earlier symlink", entries: []archiveEntry{link, escape}},
+		{name: "through a later symlink", entries: []archiveEntry{escape, link}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0o644))
+			goroot := filepath.Join(dir, "go")
+
+			err := pkg.InstallArchive(writeTarGz(t, append(goArchiveEntries("1.22.5"), tt.entries...)), goroot, "1.22.5")
+			assert.ErrorContains(t, err, `symlink "go/sub/e" points outside the installation: "a/../../secret"`)
+			assert.NoDirExists(t, goroot)
+		})
+	}
+
+	// Chains that stay inside the installation are kept
+	goroot := filepath.Join(t.TempDir(), "go")
+	err := pkg.InstallArchive(writeTarGz(t, append(goArchiveEntries("1.22.5"),
+		link,
+		archiveEntry{name: "go/sub/b", typeflag: tar.TypeSymlink, linkname: "a/bin/go"},
+	)), goroot, "1.22.5")
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(goroot, "sub", "b"))
+	assert.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\n", string(content))
+}
+
+func TestInstallDownloadsAndExtracts(t *testing.T) {
+	host := pkg.HostPlatform()
+	archive := writeTarGz(t, goArchiveEntries("1.22.5"))
+	if host.OS == "windows" {
+		archive = writeZip(t, goArchiveEntries("1.22.5"))
+	}
+	content, err := os.ReadFile(archive)
+	require.NoError(t, err)
+
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+	mockChecksum.On("GetOfficialChecksum", mock.Anything).Return(sha256Hex(string(content)), nil)
+	mockChecksum.On("Calculate", mock.Anything).Return(sha256Hex(string(content)), nil)
+	mockDownloader.On("Download", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		require.NoError(t, os.WriteFile(args.String(1), content, 0o644))
+	})
+
+	goroot := filepath.Join(t.TempDir(), "sdk", "go")
+	output := &bytes.Buffer{}
+	err = pkg.Install(pkg.DownloadConfig{
+		Version:    "go1.22.5",
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     output,
+	}, goroot)
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "Installed Go 1.22.5 in "+goroot)
+	assert.FileExists(t, filepath.Join(goroot, "bin", "go"))
+}
+
+func TestInstallChecksSpaceBeforeDownloading(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skip("free space is not available on this platform")
+	}
+	host := pkg.HostPlatform()
+	releases := []pkg.GoRelease{{
+		Version: "go1.22.5",
+		Files: []pkg.ReleaseFile{
+			{Filename: "go1.22.5." + host.OS + "-" + host.Arch + ".tar.gz", OS: host.OS, Arch: host.Arch, Kind: "archive", SHA256: "checksum", Size: 1 << 62},
+		},
+	}}
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockReleaseIndex)
+	mockChecksum.On("Releases").Return(releases, nil)
+
+	sdk := filepath.Join(t.TempDir(), "sdk")
+	err := pkg.Install(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     &bytes.Buffer{},
+	}, filepath.Join(sdk, "go"))
+	assert.ErrorContains(t, err, "not enough free space in "+sdk)
+	mockDownloader.AssertNotCalled(t, "Download", mock.Anything, mock.Anything)
+}
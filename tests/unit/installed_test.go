@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeGOROOT(t *testing.T, version string) string {
+	t.Helper()
+	goroot := t.TempDir()
+	writeFiles(t, goroot, map[string]string{"VERSION": version})
+	return goroot
+}
+
+// fakeGoCommand installs a shell script standing in for the go command
+func fakeGoCommand(t *testing.T, goroot, version string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	script := filepath.Join(t.TempDir(), "go")
+	content := "#!/bin/sh\nif [ \"$1\" = env ]; then echo '" + goroot + "'; else echo 'go version go" + version + " linux/amd64'; fi\n"
+	require.NoError(t, os.WriteFile(script, []byte(content), 0o755))
+
+	original := pkg.GoCommand
+	pkg.GoCommand = script
+	t.Cleanup(func() { pkg.GoCommand = original })
+}
+
+func TestReadGOROOTVersion(t *testing.T) {
+	version, err := pkg.ReadGOROOTVersion(fakeGOROOT(t, "go1.22.5\ntime 2024-07-02T15:40:35Z\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.22.5", version)
+
+	_, err = pkg.ReadGOROOTVersion(fakeGOROOT(t, "devel +abcdef"))
+	assert.ErrorContains(t, err, "unexpected VERSION file")
+
+	_, err = pkg.ReadGOROOTVersion(t.TempDir())
+	assert.ErrorContains(t, err, "failed to read Go version")
+}
+
+func TestDetectInstalledVersion(t *testing.T) {
+	t.Run("Given GOROOT", func(t *testing.T) {
+		goroot := fakeGOROOT(t, "go1.21.4\n")
+		tc, err := pkg.DetectInstalledVersion(goroot)
+		require.NoError(t, err)
+		assert.Equal(t, &pkg.InstalledToolchain{GOROOT: goroot, Version: "1.21.4", Source: "given GOROOT"}, tc)
+	})
+
+	t.Run("GOROOT environment variable", func(t *testing.T) {
+		goroot := fakeGOROOT(t, "go1.20.1\n")
+		t.Setenv("GOROOT", goroot)
+		tc, err := pkg.DetectInstalledVersion("")
+		require.NoError(t, err)
+		assert.Equal(t, &pkg.InstalledToolchain{GOROOT: goroot, Version: "1.20.1", Source: "$GOROOT"}, tc)
+	})
+
+	t.Run("go on PATH", func(t *testing.T) {
+		t.Setenv("GOROOT", "")
+		goroot := fakeGOROOT(t, "go1.19.13\n")
+		fakeGoCommand(t, goroot, "1.19.13")
+		tc, err := pkg.DetectInstalledVersion("")
+		require.NoError(t, err)
+		assert.Equal(t, goroot, tc.GOROOT)
+		assert.Equal(t, "1.19.13", tc.Version)
+		assert.Equal(t, pkg.GoCommand, tc.Source)
+	})
+
+	t.Run("go version fallback", func(t *testing.T) {
+		t.Setenv("GOROOT", "")
+		fakeGoCommand(t, t.TempDir(), "1.18.10")
+		tc, err := pkg.DetectInstalledVersion("")
+		require.NoError(t, err)
+		assert.Equal(t, "", tc.GOROOT)
+		assert.Equal(t, "1.18.10", tc.Version)
+	})
+
+	t.Run("Nothing installed", func(t *testing.T) {
+		t.Setenv("GOROOT", "")
+		original := pkg.GoCommand
+		pkg.GoCommand = "automatedgo-missing-go"
+		defer func() { pkg.GoCommand = original }()
+		_, err := pkg.DetectInstalledVersion("")
+		assert.ErrorContains(t, err, "no Go installation found")
+	})
+}
+
+func TestRunWithInstalledToolchain(t *testing.T) {
+	goroot := fakeGOROOT(t, "go1.22.5\n")
+	mockService := new(MockVersionChecker)
+	mockService.On("GetLatestVersion").Return("1.22.5", nil)
+	mockService.On("IsNewer", "1.22.5", "1.22.5").Return(false)
+
+	output := new(bytes.Buffer)
+	err := pkg.RunWithConfig(mockService, pkg.RunConfig{GOROOT: goroot, Input: strings.NewReader(""), Output: output})
+	require.NoError(t, err)
+	assert.Equal(t, "Installed Go found at "+goroot+" (given GOROOT)\nCurrent version: 1.22.5\nLatest version: 1.22.5\nYou have the latest version\n", output.String())
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func sha512Hex(data string) string {
+	sum := sha512.Sum512([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeManifest(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "SHA256SUMS")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	archive := sha256Hex("archive")
+	tests := []struct {
+		name     string
+		content  string
+		filename string
+		expected string
+	}{
+		{name: "GNU format", content: archive + "  go1.22.5.linux-amd64.tar.gz\n", filename: "go1.22.5.linux-amd64.tar.gz", expected: archive},
+		{name: "binary mode marker", content: archive + " *go1.22.5.linux-amd64.tar.gz\n", filename: "go1.22.5.linux-amd64.tar.gz", expected: archive},
+		{name: "directory and comments", content: "# Go 1.22.5\n\n" + archive + "  ./dist/go1.22.5.src.tar.gz\n", filename: "go1.22.5.src.tar.gz", expected: archive},
+		{name: "BSD format", content: "SHA256 (go1.22.5.linux-amd64.tar.gz) = " + archive + "\n", filename: "go1.22.5.linux-amd64.tar.gz", expected: archive},
+		{name: "SHA-512", content: sha512Hex("archive") + "  go1.22.5.linux-amd64.tar.gz\n", filename: "go1.22.5.linux-amd64.tar.gz", expected: sha512Hex("archive")},
+		{
+			name:     "release index",
+			content:  `[{"version": "go1.22.5", "stable": true, "files": [{"filename": "go1.22.5.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "sha256": "` + archive + `", "kind": "archive"}]}]`,
+			filename: "go1.22.5.linux-amd64.tar.gz",
+			expected: archive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checksum, err := pkg.LoadManifest(writeManifest(t, tt.content))
+			require.NoError(t, err)
+			sum, err := checksum.GetOfficialChecksum(tt.filename)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, sum)
+
+			_, err = checksum.GetOfficialChecksum("go1.21.0.linux-amd64.tar.gz")
+			assert.EqualError(t, err, "checksum not found for go1.21.0.linux-amd64.tar.gz in the checksum manifest")
+		})
+	}
+}
+
+func TestLoadManifestReleaseIndex(t *testing.T) {
+	content := `[{"version": "go1.22.5", "stable": true, "files": [{"filename": "go1.22.5.src.tar.gz", "sha256": "` + sha256Hex("source") + `", "kind": "source"}]}]`
+	checksum, err := pkg.LoadManifest(writeManifest(t, content))
+	require.NoError(t, err)
+
+	index, ok := checksum.(pkg.ReleaseIndex)
+	require.True(t, ok, "a saved release index should resolve installers and source tarballs")
+	releases, err := index.Releases()
+	assert.NoError(t, err)
+	assert.Len(t, releases, 1)
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{name: "empty", content: "# nothing\n", expectedError: "no checksums found in"},
+		{name: "missing filename", content: sha256Hex("archive") + "\n", expectedError: "line 1: invalid checksum line"},
+		{name: "not a digest", content: "abc123  go.tar.gz\n", expectedError: `line 1: invalid checksum "abc123": must be a SHA-256 or SHA-512 digest`},
+		{name: "mixed algorithms", content: sha256Hex("a") + "  a.tar.gz\n" + sha512Hex("b") + "  b.tar.gz\n", expectedError: "line 2: manifest mixes SHA-256 and SHA-512 checksums"},
+		{name: "invalid JSON", content: "[{", expectedError: "failed to parse release index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pkg.LoadManifest(writeManifest(t, tt.content))
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+
+	_, err := pkg.LoadManifest(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "failed to read checksum manifest")
+}
+
+func TestManifestCalculate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz")
+	require.NoError(t, os.WriteFile(file, []byte("archive"), 0o644))
+
+	for _, expected := range []string{sha256Hex("archive"), sha512Hex("archive")} {
+		checksum, err := pkg.LoadManifest(writeManifest(t, expected+"  go1.22.5.linux-amd64.tar.gz\n"))
+		require.NoError(t, err)
+		sum, err := checksum.Calculate(file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, sum)
+	}
+}
+
+func TestCheckArchive(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz")
+	bad := filepath.Join(dir, "go1.22.5.darwin-arm64.tar.gz")
+	unknown := filepath.Join(dir, "go1.22.5.windows-amd64.zip")
+	for _, f := range []string{good, bad, unknown} {
+		require.NoError(t, os.WriteFile(f, []byte("archive"), 0o644))
+	}
+
+	checksum, err := pkg.LoadManifest(writeManifest(t,
+		sha512Hex("archive")+"  go1.22.5.linux-amd64.tar.gz\n"+
+			sha512Hex("other")+"  go1.22.5.darwin-arm64.tar.gz\n"))
+	require.NoError(t, err)
+
+	result := pkg.CheckArchive(good, checksum)
+	assert.True(t, result.OK)
+	assert.Equal(t, sha512Hex("archive"), result.Actual)
+
+	result = pkg.CheckArchive(bad, checksum)
+	assert.False(t, result.OK)
+	assert.Equal(t, sha512Hex("other"), result.Expected)
+	assert.Equal(t, sha512Hex("archive"), result.Actual)
+
+	result = pkg.CheckArchive(unknown, checksum)
+	assert.False(t, result.OK)
+	assert.Equal(t, "checksum not found for go1.22.5.windows-amd64.zip in the checksum manifest", result.Error)
+}
+
+func TestDownloadGoWithSHA512Manifest(t *testing.T) {
+	dir := t.TempDir()
+	checksum, err := pkg.LoadManifest(writeManifest(t, sha512Hex("archive")+"  go1.22.5.linux-amd64.tar.gz\n"))
+	require.NoError(t, err)
+
+	// The SHA-256 computed while downloading cannot be compared with a SHA-512
+	// checksum, so the file is hashed again
+	mockDownloader := new(MockChecksumDownloader)
+	mockDownloader.On("DownloadWithHash", mock.Anything, mock.Anything).Return(sha256Hex("archive"), nil).Run(writeDownloadedFile)
+
+	err = pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   checksum,
+		Cache:      &pkg.ArchiveCache{Dir: filepath.Join(dir, "cache")},
+		Output:     &bytes.Buffer{},
+	})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+	assert.NoDirExists(t, filepath.Join(dir, "cache"), "the cache only holds archives verified with SHA-256")
+}
+
+func TestDownloadGoWithManifestPlatforms(t *testing.T) {
+	checksum, err := pkg.LoadManifest(writeManifest(t,
+		sha256Hex("archive")+"  go1.22.5.freebsd-amd64.tar.gz\n"+
+			sha256Hex("installer")+"  go1.22.5.darwin-arm64.pkg\n"+
+			sha256Hex("source")+"  go1.22.5.src.tar.gz\n"+
+			sha256Hex("older")+"  go1.21.0.linux-arm64.tar.gz\n"))
+	require.NoError(t, err)
+
+	// Platforms the built-in list does not know are accepted when the
+	// manifest lists them
+	dir := t.TempDir()
+	mockDownloader := new(MockChecksumDownloader)
+	mockDownloader.On("DownloadWithHash", mock.Anything, mock.Anything).Return(sha256Hex("archive"), nil).Run(writeDownloadedFile)
+	config := pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "freebsd",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   checksum,
+		Output:     &bytes.Buffer{},
+	}
+	assert.NoError(t, pkg.DownloadGo(config))
+	assert.FileExists(t, filepath.Join(dir, "go1.22.5.freebsd-amd64.tar.gz"))
+
+	config.TargetOS, config.Arch = "linux", "arm64"
+	err = pkg.DownloadGo(config)
+	assert.EqualError(t, err, "unsupported operating system: linux (Go 1.22.5 is available for freebsd)")
+}
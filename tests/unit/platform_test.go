@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := []struct {
+		os, arch string
+		expected pkg.Platform
+	}{
+		{"linux", "x86_64", pkg.Platform{OS: "linux", Arch: "amd64"}},
+		{"Linux", "aarch64", pkg.Platform{OS: "linux", Arch: "arm64"}},
+		{"linux", "armv7", pkg.Platform{OS: "linux", Arch: "armv6l"}},
+		{"freebsd", "armv7", pkg.Platform{OS: "freebsd", Arch: "arm"}},
+		{"win", "i686", pkg.Platform{OS: "windows", Arch: "386"}},
+		{"macos", "arm64", pkg.Platform{OS: "darwin", Arch: "arm64"}},
+		{"MacOS", "x64", pkg.Platform{OS: "darwin", Arch: "amd64"}},
+		{"linux", "riscv64", pkg.Platform{OS: "linux", Arch: "riscv64"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, pkg.NormalizePlatform(tt.os, tt.arch), tt.os+"/"+tt.arch)
+	}
+}
+
+func TestHostPlatform(t *testing.T) {
+	host := pkg.HostPlatform()
+	assert.Equal(t, runtime.GOOS, host.OS)
+	assert.Equal(t, pkg.NormalizeArch(runtime.GOOS, runtime.GOARCH), host.Arch)
+}
+
+func TestDownloadGoUsesHostPlatform(t *testing.T) {
+	host := pkg.HostPlatform()
+	ext := "tar.gz"
+	if host.OS == "windows" {
+		ext = "zip"
+	}
+	filename := "go1.22.5." + host.OS + "-" + host.Arch + "." + ext
+	releases := []pkg.GoRelease{{
+		Version: "go1.22.5",
+		Files:   []pkg.ReleaseFile{{Filename: filename, OS: host.OS, Arch: host.Arch, Kind: "archive"}},
+	}}
+
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockReleaseIndex)
+	mockChecksum.On("Releases").Return(releases, nil)
+	mockChecksum.On("GetOfficialChecksum", filename).Return("checksum", nil)
+	mockDownloader.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", mock.Anything).Return("checksum", nil)
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     output,
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "Using platform "+host.String()+" detected from this machine")
+	assert.FileExists(t, filepath.Join(dir, filename))
+}
+
+func TestDownloadGoNormalizesAliases(t *testing.T) {
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+	mockChecksum.On("GetOfficialChecksum", "go1.22.5.darwin-arm64.tar.gz").Return("checksum", nil)
+	mockDownloader.On("Download", "https://dl.google.com/go/go1.22.5.darwin-arm64.tar.gz", mock.Anything).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", mock.Anything).Return("checksum", nil)
+
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "macos",
+		Arch:       "aarch64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     &bytes.Buffer{},
+	})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "go1.22.5.darwin-arm64.tar.gz"))
+	mockDownloader.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProgressReporter(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected pkg.ProgressReporter
+	}{
+		{mode: "auto", expected: &pkg.PlainProgress{}},
+		{mode: "", expected: &pkg.PlainProgress{}},
+		{mode: "bar", expected: &pkg.BarProgress{}},
+		{mode: "plain", expected: &pkg.PlainProgress{}},
+		{mode: "json", expected: &pkg.JSONProgress{}},
+		{mode: "quiet", expected: pkg.QuietProgress{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			// A buffer is not a terminal, so auto selects plain output
+			reporter, err := pkg.NewProgressReporter(tt.mode, &bytes.Buffer{})
+			assert.NoError(t, err)
+			assert.IsType(t, tt.expected, reporter)
+		})
+	}
+
+	_, err := pkg.NewProgressReporter("fancy", &bytes.Buffer{})
+	assert.EqualError(t, err, `invalid progress mode "fancy": must be auto, bar, plain, json or quiet`)
+}
+
+func TestPlainProgress(t *testing.T) {
+	output := &bytes.Buffer{}
+	p := &pkg.PlainProgress{Output: output}
+	tracker := p.Start("go.tar.gz", 100, 0)
+	for i := 0; i < 10; i++ {
+		tracker.Add(10)
+	}
+	tracker.Finish(nil)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Equal(t, "Downloading go.tar.gz (100 bytes)", lines[0])
+	assert.Equal(t, "Downloading go.tar.gz: 10% (10 of 100 bytes)", lines[1])
+	assert.Equal(t, "Downloading go.tar.gz: 90% (90 of 100 bytes)", lines[9])
+	assert.Equal(t, "Downloaded go.tar.gz (100 bytes)", lines[10])
+	assert.Len(t, lines, 11)
+}
+
+func TestJSONProgress(t *testing.T) {
+	output := &bytes.Buffer{}
+	p := &pkg.JSONProgress{Output: output}
+	tracker := p.Start("go.tar.gz", 1000, 400)
+	tracker.Add(5)
+	tracker.Add(100)
+	p.Retry("go.tar.gz", 1, 3, errors.New("connection reset"))
+	tracker.Finish(nil)
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var event map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+
+	assert.Len(t, events, 4)
+	assert.Equal(t, map[string]any{"event": "start", "file": "go.tar.gz", "total": 1000.0, "bytes": 400.0}, events[0])
+	assert.Equal(t, "progress", events[1]["event"])
+	assert.Equal(t, 505.0, events[1]["bytes"])
+	assert.Equal(t, "retry", events[2]["event"])
+	assert.Equal(t, "connection reset", events[2]["error"])
+	assert.Equal(t, "go.tar.gz", events[2]["file"])
+	assert.Equal(t, "done", events[3]["event"])
+}
+
+func TestDownloadReportsProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("file content"))
+	}))
+	defer server.Close()
+
+	output := &bytes.Buffer{}
+	downloader := &pkg.DefaultDownloader{Progress: &pkg.PlainProgress{Output: output}}
+	assert.NoError(t, downloader.Download(server.URL, filepath.Join(t.TempDir(), "go.tar.gz")))
+
+	assert.Equal(t, "Downloading go.tar.gz (12 bytes)\nDownloaded go.tar.gz (12 bytes)\n", output.String())
+}
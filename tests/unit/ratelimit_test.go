@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterCapsThroughput(t *testing.T) {
+	limiter := pkg.NewRateLimiter(2000)
+
+	start := time.Now()
+	data, err := io.ReadAll(limiter.Reader(strings.NewReader(strings.Repeat("x", 600))))
+	elapsed := time.Since(start)
+
+	assert.NoError(t, err)
+	assert.Len(t, data, 600)
+	// The first 200 byte chunk is free, the other 400 bytes take 200ms
+	assert.GreaterOrEqual(t, elapsed, 180*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestRateLimiterIsShared(t *testing.T) {
+	limiter := pkg.NewRateLimiter(2000)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = io.ReadAll(limiter.Reader(strings.NewReader(strings.Repeat("x", 300))))
+		}()
+	}
+	wg.Wait()
+
+	// 600 bytes in total at 2000 B/s, no matter how they are split
+	assert.GreaterOrEqual(t, time.Since(start), 180*time.Millisecond)
+}
+
+func TestRateLimiterNil(t *testing.T) {
+	var limiter *pkg.RateLimiter
+	r := strings.NewReader("data")
+	assert.Same(t, r, limiter.Reader(r))
+}
+
+func TestDownloadWithRateLimit(t *testing.T) {
+	content := strings.Repeat("0123456789", 60)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "go.tar.gz")
+	downloader := &pkg.DefaultDownloader{Progress: pkg.QuietProgress{}, RateLimit: pkg.NewRateLimiter(2000)}
+
+	start := time.Now()
+	assert.NoError(t, downloader.Download(server.URL, filename))
+	assert.GreaterOrEqual(t, time.Since(start), 180*time.Millisecond)
+
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
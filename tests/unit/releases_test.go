@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testReleases = []pkg.GoRelease{
+	{
+		Version: "go1.22.5",
+		Stable:  true,
+		Files: []pkg.ReleaseFile{
+			{Filename: "go1.22.5.src.tar.gz", Kind: "source", SHA256: "src"},
+			{Filename: "go1.22.5.darwin-arm64.tar.gz", OS: "darwin", Arch: "arm64", Kind: "archive", SHA256: "darwin-archive"},
+			{Filename: "go1.22.5.darwin-arm64.pkg", OS: "darwin", Arch: "arm64", Kind: "installer", SHA256: "darwin-pkg"},
+			{Filename: "go1.22.5.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Kind: "archive", SHA256: "linux-archive"},
+			{Filename: "go1.22.5.windows-amd64.msi", OS: "windows", Arch: "amd64", Kind: "installer", SHA256: "windows-msi"},
+			{Filename: "go1.22.5.freebsd-riscv64.tar.gz", OS: "freebsd", Arch: "riscv64", Kind: "archive", SHA256: "freebsd-riscv64"},
+			{Filename: "go1.22.5.linux-riscv64.tar.gz", OS: "linux", Arch: "riscv64", Kind: "archive", SHA256: "linux-riscv64"},
+		},
+	},
+}
+
+// MockReleaseIndex is a checksum calculator that also serves the release index
+type MockReleaseIndex struct {
+	MockChecksumCalculator
+}
+
+func (m *MockReleaseIndex) Releases() ([]pkg.GoRelease, error) {
+	args := m.Called()
+	return args.Get(0).([]pkg.GoRelease), args.Error(1)
+}
+
+func TestParseFileKind(t *testing.T) {
+	for input, expected := range map[string]string{"": "archive", "archive": "archive", "installer": "installer", "source": "source"} {
+		kind, err := pkg.ParseFileKind(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, kind)
+	}
+
+	_, err := pkg.ParseFileKind("deb")
+	assert.EqualError(t, err, `invalid file kind "deb": must be archive, installer or source`)
+}
+
+func TestFindReleaseFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		version       string
+		targetOS      string
+		arch          string
+		kind          string
+		expected      string
+		expectedError string
+	}{
+		{name: "windows installer", version: "1.22.5", targetOS: "windows", arch: "amd64", kind: "installer", expected: "go1.22.5.windows-amd64.msi"},
+		{name: "macOS installer", version: "go1.22.5", targetOS: "darwin", arch: "arm64", kind: "installer", expected: "go1.22.5.darwin-arm64.pkg"},
+		{name: "source ignores the platform", version: "1.22.5", targetOS: "linux", arch: "amd64", kind: "source", expected: "go1.22.5.src.tar.gz"},
+		{name: "archive", version: "1.22.5", targetOS: "linux", arch: "amd64", kind: "archive", expected: "go1.22.5.linux-amd64.tar.gz"},
+		{name: "no linux installer", version: "1.22.5", targetOS: "linux", arch: "amd64", kind: "installer", expectedError: "no installer listed for go1.22.5 on linux/amd64"},
+		{name: "unknown release", version: "1.99.0", targetOS: "linux", arch: "amd64", kind: "archive", expectedError: "release go1.99.0 not found in the release index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := pkg.FindReleaseFile(testReleases, tt.version, tt.targetOS, tt.arch, tt.kind)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, file.Filename)
+		})
+	}
+}
+
+func TestDownloadGoSourceTarball(t *testing.T) {
+	originalBaseURL := pkg.DownloadBaseURL
+	pkg.DownloadBaseURL = "https://example.com/go/"
+	defer func() { pkg.DownloadBaseURL = originalBaseURL }()
+
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockReleaseIndex)
+	partial := filepath.Join(dir, "go1.22.5.src.tar.gz.part")
+	mockChecksum.On("Releases").Return(testReleases, nil)
+	mockDownloader.On("Download", "https://example.com/go/go1.22.5.src.tar.gz", partial).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", partial).Return("src", nil)
+
+	// No platform is needed, so nothing is prompted for
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		Kind:       "source",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     &bytes.Buffer{},
+	})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "go1.22.5.src.tar.gz"))
+	mockChecksum.AssertNotCalled(t, "GetOfficialChecksum", mock.Anything)
+	mockDownloader.AssertExpectations(t)
+}
+
+func TestDownloadGoInstallerRequiresReleaseIndex(t *testing.T) {
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		Kind:       "installer",
+		TargetOS:   "windows",
+		Arch:       "amd64",
+		Path:       t.TempDir(),
+		Downloader: new(MockDownloader),
+		Remover:    new(MockRemover),
+		Checksum:   new(MockChecksumCalculator),
+		Output:     &bytes.Buffer{},
+	})
+	assert.EqualError(t, err, "downloading the installer requires the release index")
+}
+
+func TestReleasePlatforms(t *testing.T) {
+	platforms, err := pkg.ReleasePlatforms(testReleases, "1.22.5", "archive")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"darwin":  {"arm64"},
+		"freebsd": {"riscv64"},
+		"linux":   {"amd64", "riscv64"},
+	}, platforms)
+
+	platforms, err = pkg.ReleasePlatforms(testReleases, "go1.22.5", "installer")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"darwin": {"arm64"}, "windows": {"amd64"}}, platforms)
+
+	_, err = pkg.ReleasePlatforms(testReleases, "1.99.0", "archive")
+	assert.EqualError(t, err, "release go1.99.0 not found in the release index")
+}
+
+func TestDownloadGoValidatesPlatformWithReleaseIndex(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetOS      string
+		arch          string
+		input         string
+		expectedError string
+		expectedFile  string
+	}{
+		{name: "platform missing from the built-in list", targetOS: "freebsd", arch: "riscv64", expectedFile: "go1.22.5.freebsd-riscv64.tar.gz"},
+		{name: "prompted platform", input: "linux\nriscv64\n", expectedFile: "go1.22.5.linux-riscv64.tar.gz"},
+		{name: "misspelled OS", targetOS: "linx", arch: "amd64", expectedError: "unsupported operating system: linx (did you mean linux?)"},
+		{name: "unknown OS", targetOS: "plan9", arch: "amd64", expectedError: "unsupported operating system: plan9 (Go 1.22.5 is available for darwin, freebsd, linux)"},
+		{name: "architecture of another OS", targetOS: "darwin", arch: "riscv64", expectedError: "unsupported architecture riscv64 for OS darwin (Go 1.22.5 is available for darwin on arm64, and for freebsd/riscv64, linux/riscv64)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mockDownloader := new(MockDownloader)
+			mockChecksum := new(MockReleaseIndex)
+			mockChecksum.On("Releases").Return(testReleases, nil)
+			if tt.expectedFile != "" {
+				mockChecksum.On("GetOfficialChecksum", tt.expectedFile).Return("checksum", nil)
+				mockDownloader.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
+				mockChecksum.On("Calculate", mock.Anything).Return("checksum", nil)
+			}
+
+			output := &bytes.Buffer{}
+			err := pkg.DownloadGo(pkg.DownloadConfig{
+				Version:    "1.22.5",
+				TargetOS:   tt.targetOS,
+				Arch:       tt.arch,
+				Path:       dir,
+				Downloader: mockDownloader,
+				Remover:    new(MockRemover),
+				Checksum:   mockChecksum,
+				Prompt:     tt.input != "",
+				Input:      strings.NewReader(tt.input),
+				Output:     output,
+			})
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.FileExists(t, filepath.Join(dir, tt.expectedFile))
+			if tt.input != "" {
+				assert.Contains(t, output.String(), "Enter target OS (darwin, freebsd, linux): ")
+				assert.Contains(t, output.String(), "Enter target architecture [amd64 riscv64]: ")
+			}
+		})
+	}
+}
+
+func TestDownloadGoFallsBackToBuiltInPlatforms(t *testing.T) {
+	mockChecksum := new(MockReleaseIndex)
+	mockChecksum.On("Releases").Return([]pkg.GoRelease(nil), errors.New("offline"))
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "freebsd",
+		Arch:       "riscv64",
+		Path:       t.TempDir(),
+		Downloader: new(MockDownloader),
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Output:     output,
+	})
+	assert.EqualError(t, err, "unsupported operating system: freebsd (Go 1.22.5 is available for darwin, linux, windows)")
+	assert.Contains(t, output.String(), "Warning: could not read the platforms of Go 1.22.5 from the release index (offline), using the built-in list")
+}
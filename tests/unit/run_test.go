@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -90,7 +91,7 @@ func TestRun(t *testing.T) {
 	}{
 		{
 			name:          "No version specified",
-			expectedError: errors.New("error: Either -file (-f) or -version (-v) must be specified"),
+			expectedError: errors.New("error: Either -file (-f), -version (-v) or -installed must be specified"),
 			mockSetup: func(m *MockVersionChecker) {
 				// No specific setup required for this test case
 			},
@@ -172,3 +173,52 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+func TestRunWithMultipleVersionFiles(t *testing.T) {
+	dir := t.TempDir()
+	goMod := filepath.Join(dir, "go.mod")
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	for _, name := range []string{goMod, dockerfile} {
+		assert.NoError(t, os.WriteFile(name, nil, 0o644))
+	}
+
+	tests := []struct {
+		name          string
+		combine       pkg.CombineMode
+		expected      string
+		expectedError string
+	}{
+		{"Lowest", pkg.CombineLowest, "1.21.5", ""},
+		{"Highest", pkg.CombineHighest, "1.22.1", ""},
+		{"Error", pkg.CombineError, "", "error getting current version: version sources disagree"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockVersionChecker)
+			mockService.On("GetCurrentVersion", dockerfile, "").Return("1.21.5", nil)
+			mockService.On("GetCurrentVersion", goMod, "").Return("1.22.1", nil)
+			if tt.expectedError == "" {
+				mockService.On("GetLatestVersion").Return("1.22.1", nil)
+				mockService.On("IsNewer", "1.22.1", tt.expected).Return(false)
+			}
+
+			output := new(bytes.Buffer)
+			err := pkg.RunWithConfig(mockService, pkg.RunConfig{
+				VersionFiles: []string{filepath.Join(dir, "*")},
+				Combine:      tt.combine,
+				Input:        strings.NewReader(""),
+				Output:       output,
+			})
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, output.String(), dockerfile+": 1.21.5\n"+goMod+": 1.22.1\n")
+				assert.Contains(t, output.String(), "Current version: "+tt.expected+"\n")
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func gitCommitAll(t *testing.T, repo, message string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", message},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+}
+
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	out, err := exec.Command("git", "init", "-q", repo).CombinedOutput()
+	require.NoError(t, err, string(out))
+	return repo
+}
+
+func TestIsVersionPinFile(t *testing.T) {
+	tests := map[string]bool{
+		"go.mod":                  true,
+		"tools/go.work":           true,
+		".go-version":             true,
+		".tool-versions":          true,
+		"build/Dockerfile":        true,
+		"Dockerfile.dev":          true,
+		"deploy/api.Dockerfile":   true,
+		"main.go":                 false,
+		"README.md":               false,
+		"docs/Dockerfile-example": false,
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, pkg.IsVersionPinFile(name), name)
+	}
+}
+
+func TestScanVersionPinsWorkTree(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                 "module example.com/app\n\ngo 1.22.1\n",
+		"Dockerfile":             "FROM golang:1.22.1 AS build\nFROM alpine:3.19\n",
+		"tools/.go-version":      "1.21.6\n",
+		"docker/base.Dockerfile": "FROM alpine:3.19\n",
+		"docker/Dockerfile.ci":   "FROM golang:1.21\nARG GO_VERSION=1.22\n",
+		"vendor/x/go.mod":        "module x\n\ngo 1.16\n",
+		"main.go":                "package main // go 1.10",
+	})
+
+	pins, err := pkg.ScanVersionPins(&pkg.WorkTreeSource{Root: root}, pkg.ExtractOptions{Strict: true})
+	require.NoError(t, err)
+	require.Len(t, pins, 4)
+
+	assert.Equal(t, pkg.VersionPin{Path: "Dockerfile", Version: "1.22.1", Rule: "Dockerfile FROM golang", Confidence: pkg.ConfidenceHigh}, pins[0])
+	assert.Equal(t, "docker/Dockerfile.ci", pins[1].Path)
+	assert.Contains(t, pins[1].Error, "ambiguous Go version")
+	assert.Equal(t, pkg.VersionPin{Path: "go.mod", Version: "1.22.1", Rule: "go.mod directive", Confidence: pkg.ConfidenceHigh}, pins[2])
+	assert.Equal(t, pkg.VersionPin{Path: "tools/.go-version", Version: "1.21.6", Rule: ".go-version file", Confidence: pkg.ConfidenceHigh}, pins[3])
+
+	var report bytes.Buffer
+	require.NoError(t, pkg.WriteScanReport(&report, pins))
+	assert.Contains(t, report.String(), "go.mod")
+	assert.Contains(t, report.String(), "1.22.1")
+	assert.Contains(t, report.String(), "go.mod directive (high confidence)")
+}
+
+func TestGitRevisionSourceAndDiff(t *testing.T) {
+	repo := initGitRepo(t)
+	writeFiles(t, repo, map[string]string{
+		"go.mod":     "module example.com/app\n\ngo 1.21\n",
+		"Dockerfile": "FROM golang:1.21.5\n",
+	})
+	gitCommitAll(t, repo, "initial")
+	writeFiles(t, repo, map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.22.0\n",
+		"ci/.go-version":  "1.22.0\n",
+		"docs/README.txt": "go 1.9",
+	})
+	require.NoError(t, os.Remove(filepath.Join(repo, "Dockerfile")))
+	gitCommitAll(t, repo, "bump go")
+
+	// The working tree must not be consulted when reading a revision
+	writeFiles(t, repo, map[string]string{"go.mod": "module example.com/app\n\ngo 1.30\n"})
+
+	before, err := pkg.NewGitRevisionSource(repo, "HEAD~1")
+	require.NoError(t, err)
+	after, err := pkg.NewGitRevisionSource(repo, "HEAD")
+	require.NoError(t, err)
+
+	oldPins, err := pkg.ScanVersionPins(before, pkg.ExtractOptions{Strict: true})
+	require.NoError(t, err)
+	newPins, err := pkg.ScanVersionPins(after, pkg.ExtractOptions{Strict: true})
+	require.NoError(t, err)
+	assert.Equal(t, "1.22.0", newPins[1].Version)
+
+	changes := pkg.DiffVersionPins(oldPins, newPins)
+	assert.Equal(t, []pkg.PinChange{
+		{Path: "Dockerfile", OldVersion: "1.21.5"},
+		{Path: "ci/.go-version", NewVersion: "1.22.0"},
+		{Path: "go.mod", OldVersion: "1.21", NewVersion: "1.22.0"},
+	}, changes)
+
+	var report bytes.Buffer
+	pkg.WriteDiffReport(&report, changes)
+	assert.Equal(t, "Dockerfile: removed (was 1.21.5)\nci/.go-version: added 1.22.0\ngo.mod: 1.21 -> 1.22.0\n", report.String())
+
+	report.Reset()
+	pkg.WriteDiffReport(&report, pkg.DiffVersionPins(newPins, newPins))
+	assert.Equal(t, "No Go version pins changed\n", report.String())
+}
+
+func TestDiffVersionPinsReportsErroredPins(t *testing.T) {
+	before := []pkg.VersionPin{
+		{Path: "Dockerfile", Version: "1.21.5"},
+		{Path: "go.mod", Version: "1.21"},
+		{Path: "tools/.go-version", Error: "unable to extract Go version"},
+	}
+	after := []pkg.VersionPin{
+		{Path: "Dockerfile", Error: "ambiguous Go version"},
+		{Path: "go.mod", Version: "1.21"},
+		{Path: "tools/.go-version", Error: "unable to extract Go version"},
+	}
+
+	changes := pkg.DiffVersionPins(before, after)
+	assert.Equal(t, []pkg.PinChange{
+		{Path: "Dockerfile", OldVersion: "1.21.5", NewError: "ambiguous Go version"},
+	}, changes)
+
+	var report bytes.Buffer
+	pkg.WriteDiffReport(&report, changes)
+	assert.Equal(t, "Dockerfile: 1.21.5 -> error (ambiguous Go version)\n", report.String())
+}
+
+func TestNewGitRevisionSourceUnknownRevision(t *testing.T) {
+	repo := initGitRepo(t)
+	_, err := pkg.NewGitRevisionSource(repo, "does-not-exist")
+	assert.ErrorContains(t, err, `unknown revision "does-not-exist"`)
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// installSDK installs a minimal toolchain of version into the manager
+func installSDK(t *testing.T, sdk *pkg.SDKManager, version string) {
+	require.NoError(t, pkg.InstallArchive(writeTarGz(t, goArchiveEntries(version)), sdk.GOROOT(version), version))
+}
+
+func TestSDKManager(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: filepath.Join(t.TempDir(), "sdk")}
+
+	sdks, err := sdk.Installed()
+	assert.NoError(t, err)
+	assert.Empty(t, sdks)
+	_, err = sdk.Which("")
+	assert.EqualError(t, err, "no Go version is in use in "+sdk.Root)
+
+	installSDK(t, sdk, "1.21.13")
+	installSDK(t, sdk, "1.22.5")
+	installSDK(t, sdk, "1.9.7")
+	assert.Equal(t, filepath.Join(sdk.Root, "go1.22.5"), sdk.GOROOT("go1.22.5"))
+	assert.True(t, sdk.IsInstalled("1.22.5"))
+	assert.False(t, sdk.IsInstalled("1.23.0"))
+
+	// Directories that are not toolchains are ignored
+	require.NoError(t, os.MkdirAll(filepath.Join(sdk.Root, "go1.20.0"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sdk.Root, ".go1.23.0.new-123"), 0o755))
+
+	assert.NoError(t, sdk.Use("go1.21.13"))
+	assert.NoError(t, sdk.Use("1.22.5"))
+	current, err := sdk.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.22.5", current)
+
+	version, err := pkg.ReadGOROOTVersion(filepath.Join(sdk.Root, pkg.CurrentLink))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.22.5", version)
+
+	sdks, err = sdk.Installed()
+	assert.NoError(t, err)
+	assert.Equal(t, []pkg.SDK{
+		{Version: "1.22.5", GOROOT: filepath.Join(sdk.Root, "go1.22.5"), Current: true},
+		{Version: "1.21.13", GOROOT: filepath.Join(sdk.Root, "go1.21.13")},
+		{Version: "1.9.7", GOROOT: filepath.Join(sdk.Root, "go1.9.7")},
+	}, sdks)
+
+	goroot, err := sdk.Which("")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(sdk.Root, "go1.22.5"), goroot)
+	goroot, err = sdk.Which("1.21.13")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(sdk.Root, "go1.21.13"), goroot)
+	_, err = sdk.Which("1.23.0")
+	assert.EqualError(t, err, "Go 1.23.0 is not installed in "+sdk.Root)
+}
+
+func TestSDKManagerUseRequiresInstalledVersion(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: t.TempDir()}
+	installSDK(t, sdk, "1.22.5")
+	require.NoError(t, sdk.Use("1.22.5"))
+
+	assert.EqualError(t, sdk.Use("1.23.0"), "Go 1.23.0 is not installed in "+sdk.Root)
+	current, err := sdk.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.22.5", current, "a failed switch keeps the current toolchain")
+}
+
+func TestSDKManagerUninstall(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: t.TempDir()}
+	installSDK(t, sdk, "1.21.13")
+	installSDK(t, sdk, "1.22.5")
+	require.NoError(t, sdk.Use("1.22.5"))
+
+	assert.EqualError(t, sdk.Uninstall("1.22.5"), "Go 1.22.5 is the current toolchain, switch to another version with use first")
+	assert.EqualError(t, sdk.Uninstall("1.20.0"), "Go 1.20.0 is not installed in "+sdk.Root)
+
+	assert.NoError(t, sdk.Uninstall("go1.21.13"))
+	assert.False(t, sdk.IsInstalled("1.21.13"))
+	entries, err := os.ReadDir(sdk.Root)
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"go1.22.5", pkg.CurrentLink, pkg.HistoryFile}, names)
+}
+
+func TestSDKManagerPruneCandidates(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: t.TempDir()}
+	for _, v := range []string{"1.20.14", "1.21.12", "1.21.13", "1.22.4", "1.22.5", "1.23.0"} {
+		installSDK(t, sdk, v)
+	}
+	require.NoError(t, sdk.Use("1.20.14"))
+
+	versions := func(sdks []pkg.SDK) []string {
+		var v []string
+		for _, s := range sdks {
+			v = append(v, s.Version)
+		}
+		return v
+	}
+
+	remove, err := sdk.PruneCandidates(2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.22.4", "1.21.13", "1.21.12"}, versions(remove), "the current version is kept")
+
+	remove, err = sdk.PruneCandidates(2, []string{"1.21", "go1.22.4"})
+	assert.NoError(t, err)
+	assert.Empty(t, remove, "pins keep 1.22.4 and every 1.21.x release")
+
+	remove, err = sdk.PruneCandidates(0, []string{"1.2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.23.0", "1.22.5", "1.22.4", "1.21.13", "1.21.12"}, versions(remove))
+}
+
+func TestPinnedVersions(t *testing.T) {
+	app, tools := t.TempDir(), t.TempDir()
+	writeFiles(t, app, map[string]string{"go.mod": "module example.com/app\n\ngo 1.22.1\n", "Dockerfile": "FROM golang:1.21\n"})
+	writeFiles(t, tools, map[string]string{".go-version": "go1.20.14\n"})
+
+	pins, err := pkg.PinnedVersions([]string{app, tools})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1.22.1", "1.21", "1.20.14"}, pins)
+
+	// Every version a file references is kept, not only the one scan reports
+	toolchain := t.TempDir()
+	writeFiles(t, toolchain, map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.22.0\n\ntoolchain go1.22.5\n",
+		"Dockerfile":         "FROM golang:1.21\nARG GO_VERSION=1.23\n",
+		"compose/Dockerfile": "FROM mongo:4.4\n",
+	})
+	pins, err = pkg.PinnedVersions([]string{toolchain})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1.21", "1.23", "1.22.0", "1.22.5"}, pins)
+}
+
+// fakeInstall installs a minimal toolchain and counts how often it was called
+func fakeInstall(t *testing.T, calls *[]string) pkg.InstallFunc {
+	return func(version, goroot string) error {
+		*calls = append(*calls, version)
+		return pkg.InstallArchive(writeTarGz(t, goArchiveEntries(version)), goroot, version)
+	}
+}
+
+func TestSDKManagerHistory(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: t.TempDir()}
+	var calls []string
+	require.NoError(t, sdk.Install("go1.21.13", fakeInstall(t, &calls)))
+	require.NoError(t, sdk.Use("1.21.13"))
+	require.NoError(t, sdk.Install("1.22.5", fakeInstall(t, &calls)))
+	require.NoError(t, sdk.Use("1.22.5"))
+	assert.Equal(t, []string{"1.21.13", "1.22.5"}, calls)
+
+	// Unreadable lines do not hide the rest of the history
+	f, err := os.OpenFile(filepath.Join(sdk.Root, pkg.HistoryFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	history, err := sdk.History()
+	assert.NoError(t, err)
+	require.Len(t, history, 4)
+	for i, e := range history {
+		assert.False(t, e.Time.IsZero())
+		history[i].Time = history[i].Time.Truncate(0)
+	}
+	assert.Equal(t, "install", history[0].Action)
+	assert.Equal(t, "1.21.13", history[0].Version)
+	assert.Equal(t, pkg.HistoryEntry{Time: history[1].Time, Action: "use", Version: "1.21.13"}, history[1])
+	assert.Equal(t, pkg.HistoryEntry{Time: history[3].Time, Action: "use", Version: "1.22.5", Previous: "1.21.13"}, history[3])
+}
+
+func TestSDKManagerRollback(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: t.TempDir()}
+	var calls []string
+	_, _, err := sdk.Rollback(fakeInstall(t, &calls))
+	assert.EqualError(t, err, "no previous Go version to roll back to")
+
+	require.NoError(t, sdk.Install("1.21.13", fakeInstall(t, &calls)))
+	require.NoError(t, sdk.Use("1.21.13"))
+	_, _, err = sdk.Rollback(fakeInstall(t, &calls))
+	assert.EqualError(t, err, "no previous Go version to roll back to")
+
+	require.NoError(t, sdk.Install("1.22.5", fakeInstall(t, &calls)))
+	require.NoError(t, sdk.Use("1.22.5"))
+	require.NoError(t, sdk.Use("1.22.5"))
+
+	from, to, err := sdk.Rollback(fakeInstall(t, &calls))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.22.5", from)
+	assert.Equal(t, "1.21.13", to)
+	current, err := sdk.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.21.13", current)
+
+	// Rolling back again returns to the version that was just left
+	from, to, err = sdk.Rollback(fakeInstall(t, &calls))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.21.13", "1.22.5"}, []string{from, to})
+	assert.Equal(t, []string{"1.21.13", "1.22.5"}, calls, "nothing is installed again while both versions are present")
+
+	history, err := sdk.History()
+	assert.NoError(t, err)
+	last := history[len(history)-1]
+	assert.Equal(t, "rollback", last.Action)
+	assert.Equal(t, "1.21.13", last.Previous)
+}
+
+func TestSDKManagerRollbackReinstallsPrunedVersion(t *testing.T) {
+	sdk := &pkg.SDKManager{Root: t.TempDir()}
+	var calls []string
+	require.NoError(t, sdk.Install("1.21.13", fakeInstall(t, &calls)))
+	require.NoError(t, sdk.Use("1.21.13"))
+	require.NoError(t, sdk.Install("1.22.5", fakeInstall(t, &calls)))
+	require.NoError(t, sdk.Use("1.22.5"))
+	require.NoError(t, sdk.Uninstall("1.21.13"))
+
+	from, to, err := sdk.Rollback(fakeInstall(t, &calls))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.22.5", "1.21.13"}, []string{from, to})
+	assert.Equal(t, []string{"1.21.13", "1.22.5", "1.21.13"}, calls)
+	assert.True(t, sdk.IsInstalled("1.21.13"))
+
+	// A failed download keeps the current toolchain
+	require.NoError(t, sdk.Uninstall("1.22.5"))
+	_, _, err = sdk.Rollback(func(version, goroot string) error {
+		return assert.AnError
+	})
+	assert.EqualError(t, err, "failed to reinstall Go 1.22.5: "+assert.AnError.Error())
+	current, err := sdk.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.21.13", current)
+}
+
+func TestWriteTransition(t *testing.T) {
+	tests := []struct {
+		from, to, expected string
+	}{
+		{"1.21.13", "1.22.5", "Go 1.21.13 -> 1.22.5 (upgrade)\n"},
+		{"1.22.5", "1.21.13", "Go 1.22.5 -> 1.21.13 (downgrade)\n"},
+		{"", "1.22.5", "Go 1.22.5 is now the current version\n"},
+		{"1.22.5", "1.22.5", "Go 1.22.5 is already the current version\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		pkg.WriteTransition(&out, tt.from, tt.to)
+		assert.Equal(t, tt.expected, out.String())
+	}
+}
@@ -2,6 +2,8 @@ package tests
 
 import (
 	"bytes"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Nicconike/AutomatedGo/v2/pkg"
@@ -34,6 +36,19 @@ func (m *MockFileRemover) Remove(filename string) error {
 // 	assert.NoError(t, err)
 // }
 
+func TestVersionServiceGetCurrentVersionFromStdin(t *testing.T) {
+	vs := &pkg.VersionService{Input: strings.NewReader("module example.com/app\n\ngo 1.21.4\n")}
+	version, err := vs.GetCurrentVersion("-", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.21.4", version)
+}
+
+func TestVersionServiceGetCurrentVersionStrict(t *testing.T) {
+	vs := &pkg.VersionService{Input: strings.NewReader("FROM alpine:3.19"), Strict: true}
+	_, err := vs.GetCurrentVersion("-", "")
+	assert.ErrorContains(t, err, "only low-confidence candidates found: 3.19")
+}
+
 func TestVersionServiceGetLatestVersion(t *testing.T) {
 	vs := &pkg.VersionService{}
 	_, err := vs.GetLatestVersion()
@@ -58,14 +73,16 @@ func TestVersionServiceDownloadGo(t *testing.T) {
 	}
 
 	mockChecksum.On("GetOfficialChecksum", mock.Anything).Return("checksum", nil)
-	mockDownloader.On("Download", mock.Anything, mock.Anything).Return(nil)
+	mockDownloader.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
 	mockChecksum.On("Calculate", mock.Anything).Return("checksum", nil)
 
 	input := bytes.NewBufferString("")
 	output := &bytes.Buffer{}
 
-	err := vs.DownloadGo("1.16.5", "linux", "amd64", "/tmp", input, output)
+	dir := t.TempDir()
+	err := vs.DownloadGo("1.16.5", "linux", "amd64", dir, input, output)
 	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "go1.16.5.linux-amd64.tar.gz"))
 
 	mockDownloader.AssertExpectations(t)
 	mockRemover.AssertExpectations(t)
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockVerifier struct {
+	mock.Mock
+}
+
+func (m *MockVerifier) Verify(filename, url string) error {
+	args := m.Called(filename, url)
+	return args.Error(0)
+}
+
+// newSigningKey returns a fresh key pair and its armored public key
+func newSigningKey(t *testing.T) (*openpgp.Entity, []byte) {
+	entity, err := openpgp.NewEntity("Release Signing", "", "release@example.com", nil)
+	require.NoError(t, err)
+
+	var public bytes.Buffer
+	w, err := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return entity, public.Bytes()
+}
+
+// signatureServer serves the archive content and its detached signature
+func signatureServer(t *testing.T, signer *openpgp.Entity, content string) *httptest.Server {
+	var signature bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&signature, signer, bytes.NewReader([]byte(content)), nil))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/go.tar.gz":
+			_, _ = w.Write([]byte(content))
+		case "/go.tar.gz.asc":
+			_, _ = w.Write(signature.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	signer, publicKey := newSigningKey(t)
+	server := signatureServer(t, signer, "archive")
+	defer server.Close()
+
+	verifier, err := pkg.NewSignatureVerifier(publicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "go.tar.gz")
+
+	t.Run("valid signature", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(archive, []byte("archive"), 0o644))
+		assert.NoError(t, verifier.Verify(archive, server.URL+"/go.tar.gz"))
+	})
+
+	t.Run("tampered archive", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(archive, []byte("tampered"), 0o644))
+		assert.ErrorContains(t, verifier.Verify(archive, server.URL+"/go.tar.gz"), "signature verification failed")
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		_, otherKey := newSigningKey(t)
+		other, err := pkg.NewSignatureVerifier(otherKey)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(archive, []byte("archive"), 0o644))
+		assert.ErrorContains(t, other.Verify(archive, server.URL+"/go.tar.gz"), "signature verification failed")
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		err := verifier.Verify(archive, server.URL+"/other.tar.gz")
+		assert.ErrorContains(t, err, "HTTP status 404")
+	})
+}
+
+func TestLoadSignatureVerifier(t *testing.T) {
+	_, publicKey := newSigningKey(t)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.asc")
+	require.NoError(t, os.WriteFile(keyFile, publicKey, 0o644))
+
+	_, err := pkg.LoadSignatureVerifier(keyFile)
+	assert.NoError(t, err)
+
+	_, err = pkg.LoadSignatureVerifier(filepath.Join(dir, "missing.asc"))
+	assert.ErrorContains(t, err, "failed to read signing key")
+
+	_, err = pkg.NewSignatureVerifier([]byte("not a key"))
+	assert.ErrorContains(t, err, "failed to parse signing key")
+}
+
+func TestDownloadGoRemovesArchiveWithBadSignature(t *testing.T) {
+	dir := t.TempDir()
+	partial := filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz.part")
+	url := "https://dl.google.com/go/go1.22.5.linux-amd64.tar.gz"
+
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+	mockRemover := new(MockRemover)
+	mockVerifier := new(MockVerifier)
+	mockChecksum.On("GetOfficialChecksum", "go1.22.5.linux-amd64.tar.gz").Return("checksum", nil)
+	mockDownloader.On("Download", url, partial).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", partial).Return("checksum", nil)
+	mockVerifier.On("Verify", partial, url).Return(errors.New("signature verification failed: bad signature"))
+	mockRemover.On("Remove", partial).Return(nil)
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    mockRemover,
+		Checksum:   mockChecksum,
+		Verifier:   mockVerifier,
+		Output:     output,
+	})
+	assert.EqualError(t, err, "error verifying go1.22.5.linux-amd64.tar.gz: signature verification failed: bad signature")
+	assert.Contains(t, output.String(), "Checksum verification successful!")
+	assert.NoFileExists(t, filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+	mockRemover.AssertExpectations(t)
+	mockVerifier.AssertExpectations(t)
+}
+
+func TestDownloadGoVerifiesSignature(t *testing.T) {
+	dir := t.TempDir()
+	mockDownloader := new(MockDownloader)
+	mockChecksum := new(MockChecksumCalculator)
+	mockVerifier := new(MockVerifier)
+	mockChecksum.On("GetOfficialChecksum", mock.Anything).Return("checksum", nil)
+	mockDownloader.On("Download", mock.Anything, mock.Anything).Return(nil).Run(writeDownloadedFile)
+	mockChecksum.On("Calculate", mock.Anything).Return("checksum", nil)
+	mockVerifier.On("Verify", mock.Anything, "https://dl.google.com/go/go1.22.5.linux-amd64.tar.gz").Return(nil)
+
+	output := &bytes.Buffer{}
+	err := pkg.DownloadGo(pkg.DownloadConfig{
+		Version:    "1.22.5",
+		TargetOS:   "linux",
+		Arch:       "amd64",
+		Path:       dir,
+		Downloader: mockDownloader,
+		Remover:    new(MockRemover),
+		Checksum:   mockChecksum,
+		Verifier:   mockVerifier,
+		Output:     output,
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "Signature verification successful!")
+	assert.FileExists(t, filepath.Join(dir, "go1.22.5.linux-amd64.tar.gz"))
+	mockVerifier.AssertExpectations(t)
+}
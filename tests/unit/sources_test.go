@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCombineMode(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    pkg.CombineMode
+		expectError bool
+	}{
+		{"", pkg.CombineLowest, false},
+		{"lowest", pkg.CombineLowest, false},
+		{"HIGHEST", pkg.CombineHighest, false},
+		{"error", pkg.CombineError, false},
+		{"median", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, err := pkg.ParseCombineMode(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, mode)
+		})
+	}
+}
+
+func TestExpandVersionFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mod", "b.mod", "Dockerfile"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("go 1.22"), 0o644))
+	}
+
+	t.Run("Glob and plain paths", func(t *testing.T) {
+		files, err := pkg.ExpandVersionFiles([]string{filepath.Join(dir, "*.mod"), filepath.Join(dir, "Dockerfile"), "-"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "a.mod"), filepath.Join(dir, "b.mod"), filepath.Join(dir, "Dockerfile"), "-"}, files)
+	})
+
+	t.Run("Duplicates are removed", func(t *testing.T) {
+		files, err := pkg.ExpandVersionFiles([]string{filepath.Join(dir, "a.mod"), filepath.Join(dir, "*.mod")})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "a.mod"), filepath.Join(dir, "b.mod")}, files)
+	})
+
+	t.Run("Glob without matches", func(t *testing.T) {
+		_, err := pkg.ExpandVersionFiles([]string{filepath.Join(dir, "*.json")})
+		assert.ErrorContains(t, err, "no files match pattern")
+	})
+
+	t.Run("Stdin twice", func(t *testing.T) {
+		_, err := pkg.ExpandVersionFiles([]string{"-", "-"})
+		assert.ErrorContains(t, err, "can only be read once")
+	})
+}
+
+func TestCombineVersions(t *testing.T) {
+	versions := []pkg.SourceVersion{
+		{Source: "go.mod", Version: "1.22.1"},
+		{Source: "Dockerfile", Version: "1.21.5"},
+		{Source: "-", Version: "go1.23.0"},
+	}
+
+	tests := []struct {
+		name        string
+		versions    []pkg.SourceVersion
+		mode        pkg.CombineMode
+		expected    string
+		expectError string
+	}{
+		{"Lowest", versions, pkg.CombineLowest, "1.21.5", ""},
+		{"Default is lowest", versions, "", "1.21.5", ""},
+		{"Highest", versions, pkg.CombineHighest, "go1.23.0", ""},
+		{"Error on disagreement", versions, pkg.CombineError, "", "version sources disagree: go.mod=1.22.1, Dockerfile=1.21.5, -=go1.23.0"},
+		{"Agreement", []pkg.SourceVersion{{Source: "a", Version: "1.22.1"}, {Source: "b", Version: "go1.22.1"}}, pkg.CombineError, "1.22.1", ""},
+		{"Empty", nil, pkg.CombineLowest, "", "no versions to combine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := pkg.CombineVersions(tt.versions, tt.mode)
+			if tt.expectError != "" {
+				assert.EqualError(t, err, tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
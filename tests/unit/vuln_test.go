@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nicconike/AutomatedGo/v2/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeVulnDB(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index/modules.json": `[
+			{"path": "stdlib", "vulns": [{"id": "GO-2024-0001"}, {"id": "GO-2024-0002"}]},
+			{"path": "golang.org/x/net", "vulns": [{"id": "GO-2024-0003"}]}
+		]`,
+		"ID/GO-2024-0001.json": `{"id": "GO-2024-0001", "summary": "net/http issue", "affected": [
+			{"package": {"name": "stdlib"}, "ranges": [{"type": "SEMVER", "events": [
+				{"introduced": "0"}, {"fixed": "1.21.8"}, {"introduced": "1.22.0-0"}, {"fixed": "1.22.1"}
+			]}]}
+		]}`,
+		"ID/GO-2024-0002.json": `{"id": "GO-2024-0002", "summary": "toolchain only", "affected": [
+			{"package": {"name": "toolchain"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]}
+		]}`,
+	})
+	return dir
+}
+
+func TestVulnDBVulnerabilities(t *testing.T) {
+	db, err := pkg.LoadVulnDB(writeVulnDB(t))
+	require.NoError(t, err)
+
+	tests := []struct {
+		version string
+		fixed   string
+	}{
+		{"1.20.3", "1.21.8"},
+		{"1.21.7", "1.21.8"},
+		{"1.21.8", ""},
+		{"1.22rc1", "1.22.1"},
+		{"1.22", "1.22.1"},
+		{"go1.22.1", ""},
+		{"1.23.0", ""},
+		{"devel +abc", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			vulns := db.Vulnerabilities(tt.version)
+			if tt.fixed == "" {
+				assert.Empty(t, vulns)
+				return
+			}
+			require.Len(t, vulns, 1)
+			assert.Equal(t, pkg.Vulnerability{ID: "GO-2024-0001", Summary: "net/http issue", Fixed: tt.fixed}, vulns[0])
+		})
+	}
+
+	infos := []pkg.BinaryInfo{{Path: "a", GoVersion: "1.21.0"}, {Path: "b", GoVersion: "1.22.1"}}
+	db.AnnotateBinaries(infos)
+	assert.Len(t, infos[0].Vulnerabilities, 1)
+	assert.Empty(t, infos[1].Vulnerabilities)
+}
+
+func TestLoadVulnDBOverHTTP(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir(writeVulnDB(t))))
+	defer server.Close()
+
+	db, err := pkg.LoadVulnDB(server.URL)
+	require.NoError(t, err)
+	assert.Len(t, db.Vulnerabilities("1.21.0"), 1)
+
+	_, err = pkg.LoadVulnDB(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "failed to read vulnerability index")
+}
+
+func TestWriteBinaryReportVulnerable(t *testing.T) {
+	infos := []pkg.BinaryInfo{{
+		Path:            "/usr/bin/app",
+		GoVersion:       "1.21.0",
+		Vulnerabilities: []pkg.Vulnerability{{ID: "GO-2024-0001"}, {ID: "GO-2024-0005"}},
+	}}
+	var out bytes.Buffer
+	require.NoError(t, pkg.WriteBinaryReport(&out, infos, "1.22.1"))
+	assert.Contains(t, out.String(), "outdated, vulnerable: GO-2024-0001 GO-2024-0005")
+}